@@ -1,30 +1,144 @@
-CREATE TYPE pr_status AS ENUM ('OPEN', 'MERGED');
+CREATE TYPE pr_status AS ENUM ('DRAFT', 'OPEN', 'CLOSED', 'MERGED');
 
 CREATE TABLE teams (
     team_name VARCHAR(100) PRIMARY KEY,
+    reviewer_strategy VARCHAR(32) NOT NULL DEFAULT 'least_loaded',
+    min_reviewers INTEGER NOT NULL DEFAULT 2 CHECK (min_reviewers >= 0),
+    max_reviewers INTEGER NOT NULL DEFAULT 2 CHECK (max_reviewers >= 1 AND max_reviewers >= min_reviewers),
+    max_open_reviews INTEGER NOT NULL DEFAULT 0 CHECK (max_open_reviews >= 0),
+    min_approvals INTEGER NOT NULL DEFAULT 0 CHECK (min_approvals >= 0),
+    block_on_changes_requested BOOLEAN NOT NULL DEFAULT TRUE,
+    require_lead_approval BOOLEAN NOT NULL DEFAULT FALSE,
+    lead_user_id VARCHAR(100),
+    review_sla_hours INTEGER NOT NULL DEFAULT 0 CHECK (review_sla_hours >= 0),
+    stale_action VARCHAR(16) NOT NULL DEFAULT 'flag' CHECK (stale_action IN ('flag', 'reassign')),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE users (
     user_id VARCHAR(100) PRIMARY KEY,
     username VARCHAR(100) NOT NULL,
-    team_name VARCHAR(100) NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
-    is_active BOOLEAN NOT NULL DEFAULT true
+    team_name VARCHAR(100) REFERENCES teams(team_name) ON DELETE CASCADE,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    max_open_reviews INTEGER CHECK (max_open_reviews >= 0)
+);
+
+CREATE TABLE team_memberships (
+    team_name VARCHAR(100) REFERENCES teams(team_name) ON DELETE CASCADE,
+    user_id VARCHAR(100) REFERENCES users(user_id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'lead', 'observer')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (team_name, user_id)
 );
 
+CREATE INDEX idx_team_memberships_user_id ON team_memberships(user_id);
+
 CREATE TABLE pull_requests (
     pull_request_id VARCHAR(100) PRIMARY KEY,
     pull_request_name VARCHAR(255) NOT NULL,
     author_id VARCHAR(100) NOT NULL REFERENCES users(user_id),
     status pr_status NOT NULL DEFAULT 'OPEN',
+    required_reviewers INTEGER NOT NULL DEFAULT 0,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     merged_at TIMESTAMP WITH TIME ZONE,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    changed_files JSONB,
+    required_skills JSONB,
+    requested_reviewers JSONB,
+    excluded_reviewers JSONB,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE pull_request_reviewers (
     pull_request_id VARCHAR(100) REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
     user_id VARCHAR(100) REFERENCES users(user_id) ON DELETE CASCADE,
+    source VARCHAR(32) NOT NULL DEFAULT 'team',
+    decision VARCHAR(32) NOT NULL DEFAULT 'PENDING' CHECK (decision IN ('PENDING', 'APPROVED', 'CHANGES_REQUESTED')),
+    comment TEXT NOT NULL DEFAULT '',
+    decided_at TIMESTAMP WITH TIME ZONE,
+    stale_flagged_at TIMESTAMP WITH TIME ZONE,
     assigned_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (pull_request_id, user_id)
 );
+
+CREATE TABLE reviewer_rotation_cursors (
+    team_name VARCHAR(100) PRIMARY KEY REFERENCES teams(team_name) ON DELETE CASCADE,
+    last_user_id VARCHAR(100) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE ownership_rules (
+    id SERIAL PRIMARY KEY,
+    pattern VARCHAR(255) NOT NULL,
+    team_name VARCHAR(100) NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ownership_rules_team_name ON ownership_rules(team_name);
+
+CREATE TABLE team_fallbacks (
+    team_name VARCHAR(100) REFERENCES teams(team_name) ON DELETE CASCADE,
+    fallback_team_name VARCHAR(100) REFERENCES teams(team_name) ON DELETE CASCADE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (team_name, fallback_team_name)
+);
+
+CREATE TABLE user_unavailability (
+    id SERIAL PRIMARY KEY,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_user_unavailability_user_id ON user_unavailability(user_id);
+
+CREATE TABLE user_skills (
+    user_id VARCHAR(100) REFERENCES users(user_id) ON DELETE CASCADE,
+    skill VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, skill)
+);
+
+CREATE INDEX idx_user_skills_skill ON user_skills(skill);
+
+CREATE TABLE pending_assignments (
+    pull_request_id VARCHAR(100) PRIMARY KEY REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE merge_overrides (
+    id SERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(100) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    forced_by VARCHAR(100) NOT NULL,
+    unmet_conditions TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_merge_overrides_pull_request_id ON merge_overrides(pull_request_id);
+
+CREATE TABLE reviewer_assignment_events (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(100) NOT NULL REFERENCES pull_requests(pull_request_id),
+    event_type VARCHAR(32) NOT NULL,
+    user_id VARCHAR(100) NOT NULL,
+    previous_user_id VARCHAR(100),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_reviewer_assignment_events_pull_request_id ON reviewer_assignment_events(pull_request_id, created_at);
+
+CREATE TABLE review_declines (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(100) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    author_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    reason_code VARCHAR(32) NOT NULL CHECK (reason_code IN ('conflict_of_interest', 'no_context', 'overloaded')),
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_declines_author_id ON review_declines(author_id, created_at);
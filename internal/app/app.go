@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"CodeRewievService/internal/database"
 	httpInterface "CodeRewievService/internal/http"
 	"CodeRewievService/internal/interfaces"
+	"CodeRewievService/internal/services"
 )
 
 type App struct {
@@ -39,7 +41,9 @@ func NewApp() *App {
 }
 
 type Container struct {
-	server interfaces.Server
+	server            interfaces.Server
+	assignmentWorker  interfaces.Worker
+	staleReviewWorker interfaces.Worker
 }
 
 func (a *App) Start() error {
@@ -52,6 +56,22 @@ func (a *App) Start() error {
 		}
 	}()
 
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if err := a.container.assignmentWorker.Start(a.ctx); err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to start the assignment worker: %v", err))
+		}
+	}()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if err := a.container.staleReviewWorker.Start(a.ctx); err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to start the stale review worker: %v", err))
+		}
+	}()
+
 	a.mu.Lock()
 	a.isRunning = true
 	a.mu.Unlock()
@@ -80,6 +100,12 @@ func (a *App) Stop() error {
 	if err := a.container.server.Stop(shutdownCtx, 10*time.Second); err != nil {
 		a.logger.Error(fmt.Sprintf("Failed to stop server: %v", err))
 	}
+	if err := a.container.assignmentWorker.Stop(shutdownCtx, 10*time.Second); err != nil {
+		a.logger.Error(fmt.Sprintf("Failed to stop assignment worker: %v", err))
+	}
+	if err := a.container.staleReviewWorker.Stop(shutdownCtx, 10*time.Second); err != nil {
+		a.logger.Error(fmt.Sprintf("Failed to stop stale review worker: %v", err))
+	}
 
 	// Wait for goroutines to finish with timeout
 	a.logger.Debug("Waiting for goroutines to finish")
@@ -113,7 +139,7 @@ func (a *App) WaitForShutdown() error {
 		a.logger.Info("Initializing graceful shutdown")
 		err := a.Stop()
 		if err != nil {
-			a.logger.Error("Error during graceful shutdown: ", err)
+			a.logger.Error("Error during graceful shutdown", "error", err)
 			return err
 		}
 		return nil
@@ -132,7 +158,29 @@ func (a *App) Run() error {
 		port = 0
 	}
 
-	a.container.server = httpInterface.NewServer(a.logger, db, address, port)
+	workerInterval, err := time.ParseDuration(os.Getenv("ASSIGNMENT_WORKER_INTERVAL"))
+	if err != nil {
+		workerInterval = 0
+	}
+
+	staleInterval, err := time.ParseDuration(os.Getenv("STALE_REVIEW_WORKER_INTERVAL"))
+	if err != nil {
+		staleInterval = 0
+	}
+
+	// ADMIN_TOKENS lists user_id:token pairs of the admins allowed to force merges.
+	adminTokens := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("ADMIN_TOKENS"), ",") {
+		userID, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && userID != "" && token != "" {
+			adminTokens[token] = userID
+		}
+	}
+
+	selectors := services.DefaultReviewerSelectors()
+	a.container.server = httpInterface.NewServer(a.logger, db, address, port, selectors, adminTokens)
+	a.container.assignmentWorker = services.NewAssignmentWorker(db, a.logger, selectors, workerInterval)
+	a.container.staleReviewWorker = services.NewStaleReviewWorker(db, a.logger, selectors, staleInterval)
 
 	err = a.Start()
 	if err != nil {
@@ -31,11 +31,116 @@ func InitDB() *gorm.DB {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	err = db.AutoMigrate(&entities.Team{}, &entities.User{})
-	if err != nil {
+	if err := Migrate(db); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
 	log.Println("Database connected and migrated successfully")
 	return db
 }
+
+// Migrate brings the schema of db up to date. Tables and columns of the
+// models are created by AutoMigrate, the rest comes from migrations.
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(&entities.Team{}, &entities.User{}, &entities.ReviewerRotationCursor{}, &entities.OwnershipRule{}, &entities.TeamFallback{}, &entities.UserUnavailability{}, &entities.UserSkill{}, &entities.PendingAssignment{}, &entities.MergeOverride{}, &entities.ReviewerAssignmentEvent{}, &entities.ReviewDecline{}, &entities.TeamMembership{})
+	if err != nil {
+		return err
+	}
+
+	err = db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version VARCHAR(100) PRIMARY KEY, " +
+		"applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP)").Error
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		var applied int64
+		if err := db.Table("schema_migrations").Where("version = ?", m.version).Count(&applied).Error; err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		// Statements run one by one: ALTER TYPE ... ADD VALUE cannot be used
+		// in the transaction that adds the value. They are idempotent, so a
+		// migration that failed halfway is safe to run again.
+		for _, statement := range m.statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return fmt.Errorf("migration %s: %w", m.version, err)
+			}
+		}
+
+		if err := db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", m.version).Error; err != nil {
+			return err
+		}
+		log.Printf("Applied migration %s", m.version)
+	}
+
+	return nil
+}
+
+// migration updates databases created from an older db/init.sql, which only
+// runs on a fresh volume. Each migration runs once and is recorded in
+// schema_migrations.
+type migration struct {
+	version    string
+	statements []string
+}
+
+var migrations = []migration{
+	{
+		version: "001_pull_request_statuses",
+		statements: []string{
+			"ALTER TYPE pr_status ADD VALUE IF NOT EXISTS 'DRAFT' BEFORE 'OPEN'",
+			"ALTER TYPE pr_status ADD VALUE IF NOT EXISTS 'CLOSED' AFTER 'OPEN'",
+		},
+	},
+	{
+		version: "002_pull_request_columns",
+		statements: []string{
+			"ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS required_reviewers INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP WITH TIME ZONE",
+			"ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS changed_files JSONB",
+			"ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS required_skills JSONB",
+			"ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS requested_reviewers JSONB",
+			"ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS excluded_reviewers JSONB",
+		},
+	},
+	{
+		version: "003_pull_request_reviewer_columns",
+		statements: []string{
+			"ALTER TABLE pull_request_reviewers ADD COLUMN IF NOT EXISTS source VARCHAR(32) NOT NULL DEFAULT 'team'",
+			"ALTER TABLE pull_request_reviewers ADD COLUMN IF NOT EXISTS decision VARCHAR(32) NOT NULL DEFAULT 'PENDING' " +
+				"CHECK (decision IN ('PENDING', 'APPROVED', 'CHANGES_REQUESTED'))",
+			"ALTER TABLE pull_request_reviewers ADD COLUMN IF NOT EXISTS comment TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE pull_request_reviewers ADD COLUMN IF NOT EXISTS decided_at TIMESTAMP WITH TIME ZONE",
+			"ALTER TABLE pull_request_reviewers ADD COLUMN IF NOT EXISTS stale_flagged_at TIMESTAMP WITH TIME ZONE",
+		},
+	},
+	{
+		version: "004_users_without_team",
+		statements: []string{
+			"ALTER TABLE users ALTER COLUMN team_name DROP NOT NULL",
+		},
+	},
+	{
+		version: "005_home_team_memberships",
+		statements: []string{
+			"INSERT INTO team_memberships (team_name, user_id, role) " +
+				"SELECT team_name, user_id, 'member' FROM users WHERE team_name IS NOT NULL " +
+				"ON CONFLICT DO NOTHING",
+		},
+	},
+	{
+		// The lead role follows teams.lead_user_id.
+		version: "006_lead_roles",
+		statements: []string{
+			"UPDATE team_memberships tm SET role = CASE " +
+				"WHEN tm.user_id = t.lead_user_id THEN 'lead' ELSE 'member' END " +
+				"FROM teams t WHERE t.team_name = tm.team_name AND " +
+				"(tm.role = 'lead' OR tm.user_id = t.lead_user_id)",
+		},
+	},
+}
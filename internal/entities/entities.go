@@ -2,39 +2,298 @@ package entities
 
 import "time"
 
+const (
+	ReviewerStrategyRandom      = "random"
+	ReviewerStrategyLeastLoaded = "least_loaded"
+	ReviewerStrategyRoundRobin  = "round_robin"
+
+	DefaultReviewerStrategy = ReviewerStrategyLeastLoaded
+)
+
+const (
+	DeclineReasonConflictOfInterest = "conflict_of_interest"
+	DeclineReasonNoContext          = "no_context"
+	DeclineReasonOverloaded         = "overloaded"
+)
+
+const (
+	StaleActionFlag     = "flag"
+	StaleActionReassign = "reassign"
+)
+
+const (
+	MembershipRoleMember   = "member"
+	MembershipRoleLead     = "lead"
+	MembershipRoleObserver = "observer"
+)
+
+// Membership policies decide what happens to the open pull requests of a user
+// who leaves a team.
+const (
+	MembershipPolicyKeep     = "keep"
+	MembershipPolicyReassign = "reassign"
+)
+
+const (
+	PRStatusDraft  = "DRAFT"
+	PRStatusOpen   = "OPEN"
+	PRStatusClosed = "CLOSED"
+	PRStatusMerged = "MERGED"
+)
+
+const (
+	ReviewDecisionPending          = "PENDING"
+	ReviewDecisionApproved         = "APPROVED"
+	ReviewDecisionChangesRequested = "CHANGES_REQUESTED"
+)
+
+const (
+	ReviewerSourceTeam      = "team"
+	ReviewerSourceOwnership = "ownership"
+	ReviewerSourceFallback  = "fallback"
+	ReviewerSourceSkill     = "skill"
+	ReviewerSourceRequested = "requested"
+)
+
+const (
+	AssignmentEventAssigned               = "assigned"
+	AssignmentEventReassigned             = "reassigned"
+	AssignmentEventUnassigned             = "unassigned"
+	AssignmentEventUnassignedDeactivation = "unassigned_by_deactivation"
+	AssignmentEventDeclined               = "declined"
+)
+
 type Team struct {
-	TeamName string `gorm:"primaryKey;column:team_name" json:"team_name"`
-	Members  []User `gorm:"-" json:"members"`
+	TeamName         string `gorm:"primaryKey;column:team_name" json:"team_name"`
+	ReviewerStrategy string `gorm:"column:reviewer_strategy;default:least_loaded" json:"-"`
+	MinReviewers     int    `gorm:"column:min_reviewers;not null;default:2" json:"-"`
+	MaxReviewers     int    `gorm:"column:max_reviewers;not null;default:2" json:"-"`
+	MaxOpenReviews   int    `gorm:"column:max_open_reviews;not null;default:0" json:"-"`
+
+	MinApprovals            int     `gorm:"column:min_approvals;not null;default:0" json:"-"`
+	BlockOnChangesRequested bool    `gorm:"column:block_on_changes_requested;not null;default:true" json:"-"`
+	RequireLeadApproval     bool    `gorm:"column:require_lead_approval;not null;default:false" json:"-"`
+	LeadUserID              *string `gorm:"column:lead_user_id" json:"-"`
+
+	ReviewSLAHours int    `gorm:"column:review_sla_hours;not null;default:0" json:"-"`
+	StaleAction    string `gorm:"column:stale_action;not null;default:flag" json:"-"`
+
+	Members []User `gorm:"-" json:"members"`
+}
+
+type TeamSettings struct {
+	TeamName         string   `json:"team_name"`
+	ReviewerStrategy string   `json:"reviewer_strategy"`
+	MinReviewers     int      `json:"min_reviewers"`
+	MaxReviewers     int      `json:"max_reviewers"`
+	MaxOpenReviews   int      `json:"max_open_reviews"`
+	FallbackTeams    []string `json:"fallback_teams"`
+
+	MinApprovals            int     `json:"min_approvals"`
+	BlockOnChangesRequested bool    `json:"block_on_changes_requested"`
+	RequireLeadApproval     bool    `json:"require_lead_approval"`
+	LeadUserID              *string `json:"lead_user_id"`
+
+	ReviewSLAHours int    `json:"review_sla_hours"`
+	StaleAction    string `json:"stale_action"`
+}
+
+type TeamFallback struct {
+	TeamName         string `gorm:"primaryKey;column:team_name"`
+	FallbackTeamName string `gorm:"primaryKey;column:fallback_team_name"`
+	Priority         int    `gorm:"not null;default:0"`
 }
 
+// User belongs to any number of teams through team_memberships. TeamName is
+// the home team, whose settings apply to pull requests the user authors.
 type User struct {
 	UserID   string `gorm:"primaryKey" json:"user_id"`
 	Username string `gorm:"not null" json:"username"`
-	TeamName string `gorm:"not null" json:"team_name"`
+	TeamName string `gorm:"default:null" json:"team_name"`
 	IsActive bool   `gorm:"default:true" json:"is_active"`
+
+	MaxOpenReviews *int `gorm:"column:max_open_reviews" json:"max_open_reviews,omitempty"`
+
+	Skills []string `gorm:"-" json:"skills,omitempty"`
+	// Role is the user's role in the team they are listed for.
+	Role string `gorm:"-" json:"role,omitempty"`
+}
+
+// TeamMembership puts a user in a team. Members and leads review the team's
+// pull requests, observers are never picked as reviewers.
+type TeamMembership struct {
+	TeamName  string    `gorm:"primaryKey;column:team_name" json:"team_name"`
+	UserID    string    `gorm:"primaryKey;column:user_id" json:"user_id"`
+	Role      string    `gorm:"column:role;not null;default:member" json:"role"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
 }
 
 type PullRequest struct {
-	PullRequestID   string     `gorm:"primaryKey" json:"pull_request_id"`
-	PullRequestName string     `gorm:"not null" json:"pull_request_name"`
-	AuthorID        string     `gorm:"not null" json:"author_id"`
-	Status          string     `gorm:"type:pr_status;default:'OPEN'" json:"status"`
-	CreatedAt       time.Time  `gorm:"column:created_at"`
-	MergedAt        *time.Time `gorm:"column:merged_at"`
-	UpdatedAt       time.Time  `gorm:"column:updated_at"`
+	PullRequestID     string     `gorm:"primaryKey" json:"pull_request_id"`
+	PullRequestName   string     `gorm:"not null" json:"pull_request_name"`
+	AuthorID          string     `gorm:"not null" json:"author_id"`
+	Status            string     `gorm:"type:pr_status;default:'OPEN'" json:"status"`
+	RequiredReviewers int        `gorm:"column:required_reviewers;not null;default:0" json:"required_reviewers"`
+	CreatedAt         time.Time  `gorm:"column:created_at"`
+	MergedAt          *time.Time `gorm:"column:merged_at"`
+	ClosedAt          *time.Time `gorm:"column:closed_at"`
+	UpdatedAt         time.Time  `gorm:"column:updated_at"`
 
 	Author            User                  `gorm:"foreignKey:AuthorID" json:"-"`
 	AssignedReviewers []PullRequestReviewer `gorm:"foreignKey:PullRequestID" json:"assigned_reviewers"`
+
+	// Assignment inputs are stored so that reviewers of a draft can be picked when it becomes ready.
+	ChangedFiles       []string `gorm:"column:changed_files;serializer:json" json:"-"`
+	RequiredSkills     []string `gorm:"column:required_skills;serializer:json" json:"-"`
+	RequestedReviewers []string `gorm:"column:requested_reviewers;serializer:json" json:"-"`
+	ExcludedReviewers  []string `gorm:"column:excluded_reviewers;serializer:json" json:"-"`
+	UncoveredSkills    []string `gorm:"-" json:"-"`
 }
 
 type PullRequestReviewer struct {
-	PullRequestID string    `gorm:"primaryKey" json:"pull_request_id"`
-	UserID        string    `gorm:"primaryKey" json:"user_id"`
-	AssignedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"assigned_at"`
+	PullRequestID  string     `gorm:"primaryKey" json:"pull_request_id"`
+	UserID         string     `gorm:"primaryKey" json:"user_id"`
+	Source         string     `gorm:"not null;default:team" json:"source"`
+	Decision       string     `gorm:"not null;default:PENDING" json:"decision"`
+	Comment        string     `gorm:"not null;default:''" json:"comment,omitempty"`
+	DecidedAt      *time.Time `gorm:"column:decided_at" json:"decided_at,omitempty"`
+	StaleFlaggedAt *time.Time `gorm:"column:stale_flagged_at" json:"stale_flagged_at,omitempty"`
+	AssignedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"assigned_at"`
 
 	User User `gorm:"foreignKey:UserID;references:UserID" json:"user"`
 }
 
+type UserSkill struct {
+	UserID string `gorm:"primaryKey;column:user_id"`
+	Skill  string `gorm:"primaryKey;column:skill"`
+}
+
+type UserUnavailability struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    string    `gorm:"not null;index" json:"user_id"`
+	StartsAt  time.Time `gorm:"column:starts_at;not null" json:"starts_at"`
+	EndsAt    time.Time `gorm:"column:ends_at;not null" json:"ends_at"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+type OwnershipRule struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Pattern   string    `gorm:"not null" json:"pattern"`
+	TeamName  string    `gorm:"not null;index" json:"team_name"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+type PendingAssignment struct {
+	PullRequestID string    `gorm:"primaryKey;column:pull_request_id"`
+	CreatedAt     time.Time `gorm:"column:created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at"`
+}
+
+// PullRequestReassignment reports the reviewers replaced on one pull request.
+// UnfilledSlots is how many reviewers are still missing up to
+// required_reviewers; the pull request waits for them in the pending queue.
+type PullRequestReassignment struct {
+	PullRequestID    string   `json:"pull_request_id"`
+	RemovedReviewers []string `json:"removed_reviewers"`
+	AddedReviewers   []string `json:"added_reviewers"`
+	UnfilledSlots    int      `json:"unfilled_slots"`
+}
+
+// ReassignmentReport lists the deactivated users and, for every pull request
+// they reviewed, who replaced them.
+type ReassignmentReport struct {
+	DeactivatedUsers []string                  `json:"deactivated_users"`
+	ReviewedPRs      []PullRequestReassignment `json:"reviewed_prs"`
+}
+
+// AuthoredPRUpdate reports the reviewers swapped on a pull request whose
+// author moved to another team.
+type AuthoredPRUpdate struct {
+	PullRequestID    string   `json:"pull_request_id"`
+	RemovedReviewers []string `json:"removed_reviewers"`
+	AddedReviewers   []string `json:"added_reviewers"`
+}
+
+// MembershipChange reports a user leaving FromTeam, for ToTeam or for no team
+// at all, and what happened to their open pull requests on the way.
+type MembershipChange struct {
+	User        User                      `json:"user"`
+	FromTeam    string                    `json:"from_team"`
+	ToTeam      string                    `json:"to_team,omitempty"`
+	ReviewedPRs []PullRequestReassignment `json:"reviewed_prs"`
+	AuthoredPRs []AuthoredPRUpdate        `json:"authored_prs"`
+}
+
+// ReviewDecline records a reviewer declining a pull request. Recent declines
+// keep the reviewer from being picked for the same author again.
+type ReviewDecline struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	PullRequestID string    `gorm:"column:pull_request_id;not null" json:"pull_request_id"`
+	UserID        string    `gorm:"column:user_id;not null" json:"user_id"`
+	AuthorID      string    `gorm:"column:author_id;not null" json:"author_id"`
+	ReasonCode    string    `gorm:"column:reason_code;not null" json:"reason_code"`
+	Comment       string    `gorm:"column:comment;not null;default:''" json:"comment,omitempty"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// StaleReview is a pending review that has not been decided within the SLA of the author's team.
+type StaleReview struct {
+	PullRequestID string     `json:"pull_request_id"`
+	UserID        string     `json:"user_id"`
+	TeamName      string     `json:"team_name"`
+	AssignedAt    time.Time  `json:"assigned_at"`
+	DueAt         time.Time  `json:"due_at"`
+	FlaggedAt     *time.Time `json:"flagged_at,omitempty"`
+	StaleAction   string     `json:"stale_action"`
+}
+
+// ReviewerAssignmentEvent is an append-only record of a change to the reviewers of a pull request.
+type ReviewerAssignmentEvent struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	PullRequestID  string    `gorm:"column:pull_request_id;not null;index" json:"pull_request_id"`
+	EventType      string    `gorm:"column:event_type;not null" json:"event_type"`
+	UserID         string    `gorm:"column:user_id;not null" json:"user_id"`
+	PreviousUserID *string   `gorm:"column:previous_user_id" json:"previous_user_id,omitempty"`
+	Reason         string    `gorm:"column:reason;not null;default:''" json:"reason"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// MergeOverride records a merge forced past the team merge policy. ForcedBy is
+// the admin whose token authorized it.
+type MergeOverride struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	PullRequestID   string    `gorm:"column:pull_request_id;not null;index" json:"pull_request_id"`
+	ForcedBy        string    `gorm:"column:forced_by;not null" json:"forced_by"`
+	UnmetConditions string    `gorm:"column:unmet_conditions;not null;default:''" json:"unmet_conditions"`
+	CreatedAt       time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+type ReviewerRotationCursor struct {
+	TeamName   string    `gorm:"primaryKey;column:team_name"`
+	LastUserID string    `gorm:"column:last_user_id;not null;default:''"`
+	UpdatedAt  time.Time `gorm:"column:updated_at"`
+}
+
+func (t Team) Settings() TeamSettings {
+	return TeamSettings{
+		TeamName:         t.TeamName,
+		ReviewerStrategy: t.ReviewerStrategy,
+		MinReviewers:     t.MinReviewers,
+		MaxReviewers:     t.MaxReviewers,
+		MaxOpenReviews:   t.MaxOpenReviews,
+		FallbackTeams:    []string{},
+
+		MinApprovals:            t.MinApprovals,
+		BlockOnChangesRequested: t.BlockOnChangesRequested,
+		RequireLeadApproval:     t.RequireLeadApproval,
+		LeadUserID:              t.LeadUserID,
+
+		ReviewSLAHours: t.ReviewSLAHours,
+		StaleAction:    t.StaleAction,
+	}
+}
+
 type UserReview struct {
 	UserID       string           `json:"user_id"`
 	PullRequests []PullRequestDTO `json:"pull_requests"`
@@ -48,6 +307,10 @@ func (User) TableName() string {
 	return "users"
 }
 
+func (TeamMembership) TableName() string {
+	return "team_memberships"
+}
+
 func (PullRequest) TableName() string {
 	return "pull_requests"
 }
@@ -56,6 +319,42 @@ func (PullRequestReviewer) TableName() string {
 	return "pull_request_reviewers"
 }
 
+func (UserSkill) TableName() string {
+	return "user_skills"
+}
+
+func (UserUnavailability) TableName() string {
+	return "user_unavailability"
+}
+
+func (TeamFallback) TableName() string {
+	return "team_fallbacks"
+}
+
+func (OwnershipRule) TableName() string {
+	return "ownership_rules"
+}
+
+func (PendingAssignment) TableName() string {
+	return "pending_assignments"
+}
+
+func (ReviewDecline) TableName() string {
+	return "review_declines"
+}
+
+func (ReviewerAssignmentEvent) TableName() string {
+	return "reviewer_assignment_events"
+}
+
+func (MergeOverride) TableName() string {
+	return "merge_overrides"
+}
+
+func (ReviewerRotationCursor) TableName() string {
+	return "reviewer_rotation_cursors"
+}
+
 func (p PullRequestReviewer) PrimaryKey() []string {
 	return []string{"pull_request_id", "user_id"}
 }
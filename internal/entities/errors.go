@@ -3,12 +3,35 @@ package entities
 import "errors"
 
 var (
-	ErrPRAlreadyExists       = errors.New("PR already exists")
-	ErrAuthorNotFound        = errors.New("author not found or inactive")
-	ErrNotFound              = errors.New("PR not found")
-	ErrPRAlreadyMerged       = errors.New("PR already merged")
-	ErrUserIsNotAssignedToPR = errors.New("PR is not assigned to a user")
-	ErrNoReplacement         = errors.New("no replacement found")
+	ErrPRAlreadyExists         = errors.New("PR already exists")
+	ErrAuthorNotFound          = errors.New("author not found or inactive")
+	ErrNotFound                = errors.New("PR not found")
+	ErrPRAlreadyMerged         = errors.New("PR already merged")
+	ErrUserIsNotAssignedToPR   = errors.New("PR is not assigned to a user")
+	ErrNoReplacement           = errors.New("no replacement found")
+	ErrTeamNotFound            = errors.New("team not found")
+	ErrInvalidTeamSettings     = errors.New("invalid team settings")
+	ErrInvalidOwnershipRule    = errors.New("invalid ownership rule")
+	ErrOwnershipRuleNotFound   = errors.New("ownership rule not found")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrInvalidAvailability     = errors.New("invalid availability period")
+	ErrAvailabilityNotFound    = errors.New("availability period not found")
+	ErrInvalidCapacity         = errors.New("invalid review capacity")
+	ErrReviewerNotFound        = errors.New("reviewer not found")
+	ErrReviewerInactive        = errors.New("reviewer is inactive")
+	ErrReviewerIsAuthor        = errors.New("author cannot review own PR")
+	ErrReviewerExcluded        = errors.New("reviewer is both requested and excluded")
+	ErrReviewerAlreadyAssigned = errors.New("reviewer is already assigned to PR")
+	ErrReviewerNotEligible     = errors.New("reviewer is unavailable or at review capacity")
+	ErrInvalidDecision         = errors.New("invalid review decision")
+	ErrMergeBlocked            = errors.New("merge blocked by team policy")
+	ErrMergeOverrideForbidden  = errors.New("user is not allowed to force merge")
+	ErrInvalidTransition       = errors.New("invalid PR status transition")
+	ErrPRNotOpen               = errors.New("PR is not open")
+	ErrInvalidFilter           = errors.New("invalid PR filter")
+	ErrInvalidDeclineReason    = errors.New("invalid decline reason")
+	ErrNotTeamMember           = errors.New("user is not a member of the team")
+	ErrInvalidMembershipChange = errors.New("invalid team membership change")
 )
 
 type ErrorStatsResponse struct {
@@ -1,31 +1,161 @@
 package entities
 
+import "time"
+
 type RequestCreateTeam struct {
+	TeamName         string `json:"team_name"`
+	ReviewerStrategy string `json:"reviewer_strategy,omitempty"`
+	Members          []User `json:"members"`
+}
+
+type RequestAddTeamMembers struct {
 	TeamName string `json:"team_name"`
 	Members  []User `json:"members"`
 }
 
+// RequestRemoveTeamMember takes the user out of the team. Reviews decides
+// whether their open reviews of the team's pull requests are kept or reassigned.
+type RequestRemoveTeamMember struct {
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id"`
+	Reviews  string `json:"reviews,omitempty"`
+}
+
+// RequestMoveTeamMember moves the user from FromTeamName, their home team by
+// default, to TeamName. Reviews applies to their open reviews of the old team's
+// pull requests, AuthoredPRs to the old team's reviewers of their own open pull
+// requests when the home team changes.
+type RequestMoveTeamMember struct {
+	UserID       string `json:"user_id"`
+	FromTeamName string `json:"from_team_name,omitempty"`
+	TeamName     string `json:"team_name"`
+	Reviews      string `json:"reviews,omitempty"`
+	AuthoredPRs  string `json:"authored_prs,omitempty"`
+}
+
+type RequestUpdateTeamSettings struct {
+	TeamName         string    `json:"team_name"`
+	ReviewerStrategy *string   `json:"reviewer_strategy,omitempty"`
+	MinReviewers     *int      `json:"min_reviewers,omitempty"`
+	MaxReviewers     *int      `json:"max_reviewers,omitempty"`
+	MaxOpenReviews   *int      `json:"max_open_reviews,omitempty"`
+	FallbackTeams    *[]string `json:"fallback_teams,omitempty"`
+
+	MinApprovals            *int    `json:"min_approvals,omitempty"`
+	BlockOnChangesRequested *bool   `json:"block_on_changes_requested,omitempty"`
+	RequireLeadApproval     *bool   `json:"require_lead_approval,omitempty"`
+	LeadUserID              *string `json:"lead_user_id,omitempty"`
+
+	ReviewSLAHours *int    `json:"review_sla_hours,omitempty"`
+	StaleAction    *string `json:"stale_action,omitempty"`
+}
+
 type RequestSetIsActive struct {
 	UserID   string `json:"user_id"`
 	IsActive bool   `json:"is_active"`
 }
 
+type RequestSetCapacity struct {
+	UserID         string `json:"user_id"`
+	MaxOpenReviews *int   `json:"max_open_reviews"`
+}
+
+type RequestSetSkills struct {
+	UserID string   `json:"user_id"`
+	Skills []string `json:"skills"`
+}
+
+type RequestAddUnavailability struct {
+	UserID   string    `json:"user_id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+type RequestDeleteUnavailability struct {
+	ID uint `json:"id"`
+}
+
 type RequestCreatePR struct {
-	PullRequestID   string `json:"pull_request_id"`
-	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
+	PullRequestID      string   `json:"pull_request_id"`
+	PullRequestName    string   `json:"pull_request_name"`
+	AuthorID           string   `json:"author_id"`
+	ChangedFiles       []string `json:"changed_files,omitempty"`
+	RequiredSkills     []string `json:"required_skills,omitempty"`
+	RequestedReviewers []string `json:"requested_reviewers,omitempty"`
+	ExcludedReviewers  []string `json:"excluded_reviewers,omitempty"`
+	Draft              bool     `json:"draft,omitempty"`
 }
 
 type RequestMergePR struct {
 	PullRequestID string `json:"pull_request_id"`
+	Force         bool   `json:"force,omitempty"`
+	ForcedBy      string `json:"forced_by,omitempty"`
+}
+
+type RequestChangePRStatus struct {
+	PullRequestID string `json:"pull_request_id"`
+}
+
+type RequestAddReviewer struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+}
+
+type RequestRemoveReviewer struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+	Refill        bool   `json:"refill,omitempty"`
+}
+
+type RequestDeclinePR struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+	ReasonCode    string `json:"reason_code"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+type RequestReviewPR struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+	Decision      string `json:"decision"`
+	Comment       string `json:"comment,omitempty"`
 }
 
 type RequestReassignPR struct {
 	PullRequestID string `json:"pull_request_id"`
 	OldReviewerID string `json:"old_reviewer_id"`
+	NewReviewerID string `json:"new_reviewer_id,omitempty"`
+	DryRun        bool   `json:"dry_run,omitempty"`
+}
+
+type RequestAddOwnershipRule struct {
+	Pattern  string `json:"pattern"`
+	TeamName string `json:"team_name"`
+}
+
+type RequestDeleteOwnershipRule struct {
+	ID uint `json:"id"`
 }
 
 type Error struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 }
+
+// PullRequestFilter selects pull requests for GET /pullRequest/list.
+// Empty fields do not restrict the result.
+type PullRequestFilter struct {
+	Status      string
+	AuthorID    string
+	ReviewerID  string
+	TeamName    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MergedFrom  *time.Time
+	MergedTo    *time.Time
+	// Sort is "asc" (oldest first) or "desc" (newest first, the default).
+	Sort   string
+	Limit  int
+	Cursor string
+}
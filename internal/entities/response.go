@@ -3,8 +3,11 @@ package entities
 import "time"
 
 type ResponseReassign struct {
-	PullRequest PullRequestDTO `json:"pr"`
-	ReplacedBy  string         `json:"replaced_by"`
+	PullRequest      PullRequestDTO `json:"pr"`
+	ReplacedBy       string         `json:"replaced_by"`
+	DryRun           bool           `json:"dry_run,omitempty"`
+	RemovedReviewers []string       `json:"removed_reviewers"`
+	AddedReviewers   []string       `json:"added_reviewers"`
 }
 
 type ResponseMerge struct {
@@ -13,29 +16,139 @@ type ResponseMerge struct {
 }
 
 type ResponseCreatePR struct {
-	PullRequest PullRequestDTO `json:"pr"`
+	PullRequest     PullRequestDTO `json:"pr"`
+	UncoveredSkills []string       `json:"uncovered_skills,omitempty"`
 }
 
 type ResponseAddTeam struct {
 	Team Team `json:"team"`
 }
 
-type ResponseSetIsActive struct {
+type ResponseTeamMembers struct {
+	TeamName string `json:"team_name"`
+	Members  []User `json:"members"`
+}
+
+type ResponseMembershipChange struct {
+	Membership MembershipChange `json:"membership"`
+}
+
+type ResponseTeamSettings struct {
+	Settings TeamSettings `json:"settings"`
+}
+
+type ResponseOwnershipRule struct {
+	Rule OwnershipRule `json:"rule"`
+}
+
+type ResponseOwnershipRules struct {
+	Rules []OwnershipRule `json:"rules"`
+}
+
+type ResponseUnavailability struct {
+	Unavailability UserUnavailability `json:"unavailability"`
+}
+
+type ResponseUnavailabilities struct {
+	UserID         string               `json:"user_id"`
+	Unavailability []UserUnavailability `json:"unavailability"`
+}
+
+type ResponseUserSkills struct {
+	UserID string   `json:"user_id"`
+	Skills []string `json:"skills"`
+}
+
+type ResponseSetCapacity struct {
 	User User `json:"user"`
 }
 
+type ResponseSetIsActive struct {
+	User        User                      `json:"user"`
+	ReviewedPRs []PullRequestReassignment `json:"reviewed_prs,omitempty"`
+}
+
+type ResponseMassDeactivate struct {
+	Message          string                    `json:"message"`
+	Team             string                    `json:"team"`
+	DryRun           bool                      `json:"dry_run,omitempty"`
+	DeactivatedUsers []string                  `json:"deactivated_users"`
+	ReviewedPRs      []PullRequestReassignment `json:"reviewed_prs"`
+}
+
+type ResponseGetPR struct {
+	PullRequest PullRequestDTO `json:"pr"`
+}
+
+type ResponsePullRequests struct {
+	PullRequests []PullRequestDTO `json:"pull_requests"`
+	NextCursor   string           `json:"next_cursor,omitempty"`
+}
+
+type ResponseStaleReviews struct {
+	StaleReviews []StaleReview `json:"stale_reviews"`
+}
+
+type ResponseHistory struct {
+	PullRequestID string                    `json:"pull_request_id"`
+	Events        []ReviewerAssignmentEvent `json:"events"`
+}
+
+type ResponseStatusChange struct {
+	PullRequest     PullRequestDTO `json:"pr"`
+	UncoveredSkills []string       `json:"uncovered_skills,omitempty"`
+}
+
+type ResponseReviewerChange struct {
+	PullRequest PullRequestDTO `json:"pr"`
+	ReplacedBy  string         `json:"replaced_by,omitempty"`
+}
+
+type ResponseReview struct {
+	PullRequest PullRequestDTO `json:"pr"`
+}
+
 type PullRequestDTO struct {
-	PullRequestID     string   `json:"pull_request_id"`
-	PullRequestName   string   `json:"pull_request_name"`
-	AuthorID          string   `json:"author_id"`
-	Status            string   `json:"status"`
-	AssignedReviewers []string `json:"assigned_reviewers,omitempty"`
+	PullRequestID     string        `json:"pull_request_id"`
+	PullRequestName   string        `json:"pull_request_name"`
+	AuthorID          string        `json:"author_id"`
+	Status            string        `json:"status"`
+	AssignedReviewers []ReviewerDTO `json:"assigned_reviewers"`
+	FallbackReviewers []string      `json:"fallback_reviewers,omitempty"`
+	UnfilledSlots     int           `json:"unfilled_slots"`
+	UnderReviewed     bool          `json:"under_reviewed"`
+	CreatedAt         time.Time     `json:"created_at"`
+	MergedAt          *time.Time    `json:"merged_at,omitempty"`
+	ClosedAt          *time.Time    `json:"closed_at,omitempty"`
+}
+
+type ReviewerDTO struct {
+	UserID    string     `json:"user_id"`
+	Source    string     `json:"source"`
+	Decision  string     `json:"decision"`
+	Comment   string     `json:"comment,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
 }
 
 func (pr PullRequest) ToResponse() PullRequestDTO {
-	reviewerIDs := make([]string, len(pr.AssignedReviewers))
+	reviewers := make([]ReviewerDTO, len(pr.AssignedReviewers))
+	var fallbackIDs []string
 	for i, reviewer := range pr.AssignedReviewers {
-		reviewerIDs[i] = reviewer.UserID
+		reviewers[i] = ReviewerDTO{
+			UserID:    reviewer.UserID,
+			Source:    reviewer.Source,
+			Decision:  reviewer.Decision,
+			Comment:   reviewer.Comment,
+			DecidedAt: reviewer.DecidedAt,
+		}
+		if reviewer.Source == ReviewerSourceFallback {
+			fallbackIDs = append(fallbackIDs, reviewer.UserID)
+		}
+	}
+
+	unfilledSlots := 0
+	if pr.Status == PRStatusOpen {
+		unfilledSlots = max(0, pr.RequiredReviewers-len(pr.AssignedReviewers))
 	}
 
 	return PullRequestDTO{
@@ -43,6 +156,12 @@ func (pr PullRequest) ToResponse() PullRequestDTO {
 		PullRequestName:   pr.PullRequestName,
 		AuthorID:          pr.AuthorID,
 		Status:            pr.Status,
-		AssignedReviewers: reviewerIDs,
+		AssignedReviewers: reviewers,
+		FallbackReviewers: fallbackIDs,
+		UnfilledSlots:     unfilledSlots,
+		UnderReviewed:     unfilledSlots > 0,
+		CreatedAt:         pr.CreatedAt,
+		MergedAt:          pr.MergedAt,
+		ClosedAt:          pr.ClosedAt,
 	}
 }
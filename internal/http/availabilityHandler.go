@@ -0,0 +1,133 @@
+package http
+
+import (
+	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
+	"CodeRewievService/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log/slog"
+	"net/http"
+)
+
+type AvailabilityHandler struct {
+	availabilityService interfaces.AvailabilityServiceInterface
+	logger              *slog.Logger
+}
+
+func NewAvailabilityHandler(logger *slog.Logger, db *gorm.DB) *AvailabilityHandler {
+	return &AvailabilityHandler{
+		availabilityService: services.NewAvailabilityService(db),
+		logger:              logger,
+	}
+}
+
+func (handler *AvailabilityHandler) AddUnavailability(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestAddUnavailability
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	if requestBody.UserID == "" {
+		handler.writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	period, err := handler.availabilityService.AddUnavailability(&entities.UserUnavailability{
+		UserID:   requestBody.UserID,
+		StartsAt: requestBody.StartsAt,
+		EndsAt:   requestBody.EndsAt,
+		Reason:   requestBody.Reason,
+	})
+
+	if errors.Is(err, entities.ErrInvalidAvailability) {
+		handler.logger.Error(fmt.Sprintf("Invalid availability period: %s", err))
+		handler.writeError(w, http.StatusBadRequest, "INVALID_PERIOD", err.Error())
+		return
+	}
+
+	if errors.Is(err, entities.ErrUserNotFound) {
+		handler.logger.Error(fmt.Sprintf("User not found: %s", requestBody.UserID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Availability creation error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusCreated, entities.ResponseUnavailability{
+		Unavailability: *period,
+	})
+}
+
+func (handler *AvailabilityHandler) ListUnavailability(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		handler.writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	periods, err := handler.availabilityService.ListUnavailability(userID)
+	if errors.Is(err, entities.ErrInvalidAvailability) {
+		handler.writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to list availability: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseUnavailabilities{
+		UserID:         userID,
+		Unavailability: periods,
+	})
+}
+
+func (handler *AvailabilityHandler) DeleteUnavailability(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestDeleteUnavailability
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	err := handler.availabilityService.DeleteUnavailability(requestBody.ID)
+
+	if errors.Is(err, entities.ErrAvailabilityNotFound) {
+		handler.logger.Error(fmt.Sprintf("Availability period not found: %d", requestBody.ID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Availability deletion error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *AvailabilityHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to encode response to json: %s", err))
+	}
+}
+
+func (handler *AvailabilityHandler) writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	handler.writeJSON(w, statusCode, entities.Error{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
+	"CodeRewievService/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log/slog"
+	"net/http"
+)
+
+type OwnershipHandler struct {
+	ownershipService interfaces.OwnershipServiceInterface
+	logger           *slog.Logger
+}
+
+func NewOwnershipHandler(logger *slog.Logger, db *gorm.DB) *OwnershipHandler {
+	return &OwnershipHandler{
+		ownershipService: services.NewOwnershipService(db),
+		logger:           logger,
+	}
+}
+
+func (handler *OwnershipHandler) AddRule(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestAddOwnershipRule
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	rule, err := handler.ownershipService.AddRule(&entities.OwnershipRule{
+		Pattern:  requestBody.Pattern,
+		TeamName: requestBody.TeamName,
+	})
+
+	if errors.Is(err, entities.ErrInvalidOwnershipRule) {
+		handler.logger.Error(fmt.Sprintf("Invalid ownership rule: %s", err))
+		handler.writeError(w, http.StatusBadRequest, "INVALID_RULE", err.Error())
+		return
+	}
+
+	if errors.Is(err, entities.ErrTeamNotFound) {
+		handler.logger.Error(fmt.Sprintf("Team not found: %s", requestBody.TeamName))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Ownership rule creation error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusCreated, entities.ResponseOwnershipRule{
+		Rule: *rule,
+	})
+}
+
+func (handler *OwnershipHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+
+	rules, err := handler.ownershipService.ListRules(teamName)
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to list ownership rules: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseOwnershipRules{
+		Rules: rules,
+	})
+}
+
+func (handler *OwnershipHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestDeleteOwnershipRule
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	err := handler.ownershipService.DeleteRule(requestBody.ID)
+
+	if errors.Is(err, entities.ErrOwnershipRuleNotFound) {
+		handler.logger.Error(fmt.Sprintf("Ownership rule not found: %d", requestBody.ID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Ownership rule deletion error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *OwnershipHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to encode response to json: %s", err))
+	}
+}
+
+func (handler *OwnershipHandler) writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	handler.writeJSON(w, statusCode, entities.Error{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -4,24 +4,32 @@ import (
 	"CodeRewievService/internal/entities"
 	"CodeRewievService/internal/interfaces"
 	"CodeRewievService/internal/services"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 )
 
+// adminTokenHeader carries the token an admin authenticates with to force a merge.
+const adminTokenHeader = "X-Admin-Token"
+
 type PrHandler struct {
 	prService interfaces.PullRequestServiceInterface
 	logger    *slog.Logger
+	// adminTokens maps admin tokens to the user IDs of their admins.
+	adminTokens map[string]string
 }
 
-func NewPrHandler(logger *slog.Logger, db *gorm.DB) *PrHandler {
+func NewPrHandler(logger *slog.Logger, db *gorm.DB, selectors map[string]interfaces.ReviewerSelector, adminTokens map[string]string) *PrHandler {
 	return &PrHandler{
-		prService: services.NewPullRequestService(db),
-		logger:    logger,
+		prService:   services.NewPullRequestService(db, selectors),
+		logger:      logger,
+		adminTokens: adminTokens,
 	}
 }
 
@@ -33,11 +41,20 @@ func (handler *PrHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	status := entities.PRStatusOpen
+	if requestBody.Draft {
+		status = entities.PRStatusDraft
+	}
+
 	pr, err := handler.prService.Create(&entities.PullRequest{
-		PullRequestID:   requestBody.PullRequestID,
-		PullRequestName: requestBody.PullRequestName,
-		AuthorID:        requestBody.AuthorID,
-		Status:          "OPEN",
+		PullRequestID:      requestBody.PullRequestID,
+		PullRequestName:    requestBody.PullRequestName,
+		AuthorID:           requestBody.AuthorID,
+		Status:             status,
+		ChangedFiles:       requestBody.ChangedFiles,
+		RequiredSkills:     requestBody.RequiredSkills,
+		RequestedReviewers: requestBody.RequestedReviewers,
+		ExcludedReviewers:  requestBody.ExcludedReviewers,
 	})
 
 	if errors.Is(err, entities.ErrPRAlreadyExists) {
@@ -64,6 +81,10 @@ func (handler *PrHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if handler.writeReviewerError(w, err) {
+		return
+	}
+
 	if err != nil {
 		handler.logger.Error(fmt.Sprintf("PR creation error: %s", err))
 		w.WriteHeader(http.StatusInternalServerError)
@@ -73,7 +94,8 @@ func (handler *PrHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(entities.ResponseCreatePR{
-		PullRequest: pr.ToResponse(),
+		PullRequest:     pr.ToResponse(),
+		UncoveredSkills: pr.UncoveredSkills,
 	})
 	if err != nil {
 		handler.logger.Error(fmt.Sprintf("Failed to encode pr to json: %s", err))
@@ -89,7 +111,39 @@ func (handler *PrHandler) MergePR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pr, err := handler.prService.Merge(requestBody.PullRequestID)
+	var forcedBy string
+	if requestBody.Force {
+		admin, ok := handler.admin(r)
+		if !ok {
+			handler.logger.Error(fmt.Sprintf("Force merge rejected: pr=%s: missing or unknown admin token", requestBody.PullRequestID))
+			handler.writeError(w, http.StatusForbidden, "FORBIDDEN", "force merge requires a valid "+adminTokenHeader+" header")
+			return
+		}
+		if requestBody.ForcedBy != "" && requestBody.ForcedBy != admin {
+			handler.logger.Error(fmt.Sprintf("Force merge rejected: pr=%s: forced_by %q does not match the admin token", requestBody.PullRequestID, requestBody.ForcedBy))
+			handler.writeError(w, http.StatusForbidden, "FORBIDDEN", "forced_by does not match the admin token")
+			return
+		}
+		forcedBy = admin
+	}
+
+	pr, err := handler.prService.Merge(requestBody.PullRequestID, requestBody.Force, forcedBy)
+
+	if errors.Is(err, entities.ErrMergeOverrideForbidden) {
+		handler.logger.Error(fmt.Sprintf("Force merge rejected: %s", err))
+		handler.writeError(w, http.StatusForbidden, "FORBIDDEN", err.Error())
+		return
+	}
+
+	if handler.writeStatusError(w, err) {
+		return
+	}
+
+	if errors.Is(err, entities.ErrMergeBlocked) {
+		handler.logger.Error(fmt.Sprintf("Merge blocked: pr=%s: %s", requestBody.PullRequestID, err))
+		handler.writeError(w, http.StatusConflict, "MERGE_BLOCKED", err.Error())
+		return
+	}
 
 	if errors.Is(err, entities.ErrNotFound) {
 		handler.logger.Error(fmt.Sprint("Author / team not found"))
@@ -103,6 +157,12 @@ func (handler *PrHandler) MergePR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("PR merge error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(entities.ResponseMerge{
@@ -123,7 +183,15 @@ func (handler *PrHandler) ReassignPR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pr, userId, err := handler.prService.Reassign(requestBody.PullRequestID, requestBody.OldReviewerID)
+	dryRun, err := dryRunRequested(r)
+	if err != nil {
+		handler.writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+	dryRun = dryRun || requestBody.DryRun
+
+	pr, added, err := handler.prService.Reassign(requestBody.PullRequestID, requestBody.OldReviewerID,
+		requestBody.NewReviewerID, dryRun)
 
 	if errors.Is(err, entities.ErrPRAlreadyMerged) {
 		handler.logger.Error(fmt.Sprintf("User not assigned to this pr: %s", requestBody.PullRequestID))
@@ -137,6 +205,10 @@ func (handler *PrHandler) ReassignPR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if handler.writeStatusError(w, err) {
+		return
+	}
+
 	if errors.Is(err, entities.ErrUserIsNotAssignedToPR) {
 		handler.logger.Error(fmt.Sprintf("Reviewer is not assigned to this PR: pr=%s",
 			requestBody.PullRequestID))
@@ -162,6 +234,10 @@ func (handler *PrHandler) ReassignPR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if handler.writeReviewerError(w, err) {
+		return
+	}
+
 	if errors.Is(err, entities.ErrNoReplacement) {
 		handler.logger.Error(fmt.Sprint("No replacement found for reviewer"))
 
@@ -183,11 +259,465 @@ func (handler *PrHandler) ReassignPR(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(entities.ResponseReassign{
-		PullRequest: pr.ToResponse(),
-		ReplacedBy:  userId,
+		PullRequest:      pr.ToResponse(),
+		ReplacedBy:       added[0],
+		DryRun:           dryRun,
+		RemovedReviewers: []string{requestBody.OldReviewerID},
+		AddedReviewers:   added,
 	})
 	if err != nil {
 		handler.logger.Error(fmt.Sprintf("Failed to encode pr to json: %s", err))
 		return
 	}
 }
+
+func (handler *PrHandler) ReviewPR(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestReviewPR
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	pr, err := handler.prService.Review(requestBody.PullRequestID, requestBody.UserID, requestBody.Decision, requestBody.Comment)
+
+	if errors.Is(err, entities.ErrInvalidDecision) {
+		handler.logger.Error(fmt.Sprintf("Invalid review decision: %s", err))
+		handler.writeError(w, http.StatusBadRequest, "INVALID_DECISION", err.Error())
+		return
+	}
+
+	if errors.Is(err, entities.ErrPRAlreadyMerged) {
+		handler.logger.Error(fmt.Sprintf("Cannot review merged PR: %s", requestBody.PullRequestID))
+		handler.writeError(w, http.StatusConflict, "PR_MERGED", "cannot review merged PR")
+		return
+	}
+
+	if handler.writeStatusError(w, err) {
+		return
+	}
+
+	if errors.Is(err, entities.ErrUserIsNotAssignedToPR) {
+		handler.logger.Error(fmt.Sprintf("Reviewer is not assigned to this PR: pr=%s", requestBody.PullRequestID))
+		handler.writeError(w, http.StatusConflict, "NOT_ASSIGNED", "reviewer not assigned to this PR")
+		return
+	}
+
+	if errors.Is(err, entities.ErrNotFound) {
+		handler.logger.Error(fmt.Sprintf("PR is not found: pr=%s", requestBody.PullRequestID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("PR review error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseReview{
+		PullRequest: pr.ToResponse(),
+	})
+}
+
+func (handler *PrHandler) DeclinePR(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestDeclinePR
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	pr, replacedBy, err := handler.prService.Decline(requestBody.PullRequestID, requestBody.UserID,
+		requestBody.ReasonCode, requestBody.Comment)
+
+	if errors.Is(err, entities.ErrInvalidDeclineReason) {
+		handler.logger.Error(fmt.Sprintf("Invalid decline reason: %s", err))
+		handler.writeError(w, http.StatusBadRequest, "INVALID_REASON", err.Error())
+		return
+	}
+
+	if errors.Is(err, entities.ErrPRAlreadyMerged) {
+		handler.logger.Error(fmt.Sprintf("Cannot decline merged PR: %s", requestBody.PullRequestID))
+		handler.writeError(w, http.StatusConflict, "PR_MERGED", "cannot decline merged PR")
+		return
+	}
+
+	if handler.writeStatusError(w, err) {
+		return
+	}
+
+	if errors.Is(err, entities.ErrUserIsNotAssignedToPR) {
+		handler.logger.Error(fmt.Sprintf("Reviewer is not assigned to this PR: pr=%s", requestBody.PullRequestID))
+		handler.writeError(w, http.StatusConflict, "NOT_ASSIGNED", "reviewer not assigned to this PR")
+		return
+	}
+
+	if errors.Is(err, entities.ErrNotFound) {
+		handler.logger.Error(fmt.Sprintf("PR / reviewer is not found: pr=%s", requestBody.PullRequestID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("PR decline error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseReviewerChange{
+		PullRequest: pr.ToResponse(),
+		ReplacedBy:  replacedBy,
+	})
+}
+
+func (handler *PrHandler) AddReviewer(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestAddReviewer
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	pr, err := handler.prService.AddReviewer(requestBody.PullRequestID, requestBody.UserID)
+	if handler.writeReviewerChangeError(w, requestBody.PullRequestID, err) {
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseReviewerChange{
+		PullRequest: pr.ToResponse(),
+	})
+}
+
+func (handler *PrHandler) RemoveReviewer(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestRemoveReviewer
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	pr, replacedBy, err := handler.prService.RemoveReviewer(requestBody.PullRequestID, requestBody.UserID, requestBody.Refill)
+	if handler.writeReviewerChangeError(w, requestBody.PullRequestID, err) {
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseReviewerChange{
+		PullRequest: pr.ToResponse(),
+		ReplacedBy:  replacedBy,
+	})
+}
+
+// writeReviewerChangeError reports why reviewers of a PR could not be changed.
+// It returns false when err is nil.
+func (handler *PrHandler) writeReviewerChangeError(w http.ResponseWriter, prID string, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, entities.ErrPRAlreadyMerged) {
+		handler.logger.Error(fmt.Sprintf("Cannot change reviewers of merged PR: %s", prID))
+		handler.writeError(w, http.StatusConflict, "PR_MERGED", "cannot change reviewers of merged PR")
+		return true
+	}
+
+	if handler.writeStatusError(w, err) || handler.writeReviewerError(w, err) {
+		return true
+	}
+
+	if errors.Is(err, entities.ErrUserIsNotAssignedToPR) {
+		handler.logger.Error(fmt.Sprintf("Reviewer is not assigned to this PR: pr=%s", prID))
+		handler.writeError(w, http.StatusConflict, "NOT_ASSIGNED", "reviewer not assigned to this PR")
+		return true
+	}
+
+	if errors.Is(err, entities.ErrNotFound) {
+		handler.logger.Error(fmt.Sprintf("PR / reviewer is not found: pr=%s", prID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return true
+	}
+
+	handler.logger.Error(fmt.Sprintf("PR reviewer change error: %s", err))
+	w.WriteHeader(http.StatusInternalServerError)
+	return true
+}
+
+func (handler *PrHandler) GetPR(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		handler.writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id is required")
+		return
+	}
+
+	pr, err := handler.prService.Get(prID)
+
+	if errors.Is(err, entities.ErrNotFound) {
+		handler.logger.Error(fmt.Sprintf("PR is not found: pr=%s", prID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to get PR: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseGetPR{
+		PullRequest: pr.ToResponse(),
+	})
+}
+
+func (handler *PrHandler) GetPRHistory(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		handler.writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id is required")
+		return
+	}
+
+	events, err := handler.prService.History(prID)
+
+	if errors.Is(err, entities.ErrNotFound) {
+		handler.logger.Error(fmt.Sprintf("PR is not found: pr=%s", prID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to get PR history: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseHistory{
+		PullRequestID: prID,
+		Events:        events,
+	})
+}
+
+func (handler *PrHandler) ListStaleReviews(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+
+	stale, err := handler.prService.Stale(teamName)
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to list stale reviews: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseStaleReviews{
+		StaleReviews: stale,
+	})
+}
+
+func (handler *PrHandler) ListPRs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parsePullRequestFilter(r)
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Invalid PR filter: %s", err))
+		handler.writeError(w, http.StatusBadRequest, "INVALID_FILTER", err.Error())
+		return
+	}
+
+	prs, nextCursor, err := handler.prService.List(filter)
+
+	if errors.Is(err, entities.ErrInvalidFilter) {
+		handler.logger.Error(fmt.Sprintf("Invalid PR filter: %s", err))
+		handler.writeError(w, http.StatusBadRequest, "INVALID_FILTER", err.Error())
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to list PRs: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := entities.ResponsePullRequests{
+		PullRequests: make([]entities.PullRequestDTO, len(prs)),
+		NextCursor:   nextCursor,
+	}
+	for i, pr := range prs {
+		response.PullRequests[i] = pr.ToResponse()
+	}
+
+	handler.writeJSON(w, http.StatusOK, response)
+}
+
+// dryRunRequested reads the optional dry_run query parameter.
+func dryRunRequested(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("dry_run")
+	if value == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("dry_run must be a boolean")
+	}
+	return dryRun, nil
+}
+
+// parsePullRequestFilter reads the filter of GET /pullRequest/list from the query string.
+// Dates are RFC 3339 timestamps.
+func parsePullRequestFilter(r *http.Request) (*entities.PullRequestFilter, error) {
+	query := r.URL.Query()
+	filter := &entities.PullRequestFilter{
+		Status:     query.Get("status"),
+		AuthorID:   query.Get("author_id"),
+		ReviewerID: query.Get("reviewer_id"),
+		TeamName:   query.Get("team_name"),
+		Sort:       query.Get("sort"),
+		Cursor:     query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("%w: limit must be an integer", entities.ErrInvalidFilter)
+		}
+		filter.Limit = parsed
+	}
+
+	dates := map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"merged_from":  &filter.MergedFrom,
+		"merged_to":    &filter.MergedTo,
+	}
+	for name, target := range dates {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", entities.ErrInvalidFilter, name)
+		}
+		*target = &parsed
+	}
+
+	return filter, nil
+}
+
+func (handler *PrHandler) MarkReadyPR(w http.ResponseWriter, r *http.Request) {
+	handler.changeStatus(w, r, handler.prService.MarkReady)
+}
+
+func (handler *PrHandler) ClosePR(w http.ResponseWriter, r *http.Request) {
+	handler.changeStatus(w, r, handler.prService.Close)
+}
+
+func (handler *PrHandler) ReopenPR(w http.ResponseWriter, r *http.Request) {
+	handler.changeStatus(w, r, handler.prService.Reopen)
+}
+
+func (handler *PrHandler) changeStatus(w http.ResponseWriter, r *http.Request, change func(prID string) (*entities.PullRequest, error)) {
+	var requestBody entities.RequestChangePRStatus
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	pr, err := change(requestBody.PullRequestID)
+
+	if errors.Is(err, entities.ErrNotFound) {
+		handler.logger.Error(fmt.Sprintf("PR is not found: pr=%s", requestBody.PullRequestID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if handler.writeStatusError(w, err) {
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("PR status change error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseStatusChange{
+		PullRequest:     pr.ToResponse(),
+		UncoveredSkills: pr.UncoveredSkills,
+	})
+}
+
+// writeStatusError reports an action that the current PR status does not allow.
+// It returns false when err is not a status error.
+func (handler *PrHandler) writeStatusError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, entities.ErrInvalidTransition):
+		handler.logger.Error(fmt.Sprintf("Invalid PR transition: %s", err))
+		handler.writeError(w, http.StatusConflict, "INVALID_TRANSITION", err.Error())
+	case errors.Is(err, entities.ErrPRNotOpen):
+		handler.logger.Error(fmt.Sprintf("PR is not open: %s", err))
+		handler.writeError(w, http.StatusConflict, "PR_NOT_OPEN", err.Error())
+	case errors.Is(err, entities.ErrAuthorNotFound):
+		handler.logger.Error(fmt.Sprintf("PR author cannot get reviewers: %s", err))
+		handler.writeError(w, http.StatusConflict, "AUTHOR_NOT_IN_TEAM", err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// admin returns the admin whose token the request carries in adminTokenHeader.
+func (handler *PrHandler) admin(r *http.Request) (string, bool) {
+	token := r.Header.Get(adminTokenHeader)
+	if token == "" {
+		return "", false
+	}
+
+	for adminToken, userID := range handler.adminTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+			return userID, true
+		}
+	}
+	return "", false
+}
+
+// writeReviewerError reports why an explicitly chosen reviewer was rejected.
+// It returns false when err is not a reviewer validation error.
+func (handler *PrHandler) writeReviewerError(w http.ResponseWriter, err error) bool {
+	var statusCode int
+	var code string
+
+	switch {
+	case errors.Is(err, entities.ErrReviewerNotFound):
+		statusCode, code = http.StatusNotFound, "REVIEWER_NOT_FOUND"
+	case errors.Is(err, entities.ErrReviewerInactive):
+		statusCode, code = http.StatusConflict, "REVIEWER_INACTIVE"
+	case errors.Is(err, entities.ErrReviewerIsAuthor):
+		statusCode, code = http.StatusConflict, "REVIEWER_IS_AUTHOR"
+	case errors.Is(err, entities.ErrReviewerExcluded):
+		statusCode, code = http.StatusBadRequest, "REVIEWER_EXCLUDED"
+	case errors.Is(err, entities.ErrReviewerAlreadyAssigned):
+		statusCode, code = http.StatusConflict, "REVIEWER_ALREADY_ASSIGNED"
+	case errors.Is(err, entities.ErrReviewerNotEligible):
+		statusCode, code = http.StatusConflict, "REVIEWER_NOT_ELIGIBLE"
+	default:
+		return false
+	}
+
+	handler.logger.Error(fmt.Sprintf("Reviewer rejected: %s", err))
+	handler.writeError(w, statusCode, code, err.Error())
+	return true
+}
+
+func (handler *PrHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to encode response to json: %s", err))
+	}
+}
+
+func (handler *PrHandler) writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	handler.writeJSON(w, statusCode, entities.Error{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"CodeRewievService/internal/interfaces"
 	"context"
 	"errors"
 	"fmt"
@@ -22,13 +23,15 @@ type Server struct {
 	port      int
 	logger    *slog.Logger
 
-	userHandler  *UserHandler
-	teamHandler  *TeamHandler
-	prHandler    *PrHandler
-	statsHandler *StatsHandler
+	userHandler         *UserHandler
+	teamHandler         *TeamHandler
+	prHandler           *PrHandler
+	statsHandler        *StatsHandler
+	ownershipHandler    *OwnershipHandler
+	availabilityHandler *AvailabilityHandler
 }
 
-func NewServer(logger *slog.Logger, db *gorm.DB, address string, port int) *Server {
+func NewServer(logger *slog.Logger, db *gorm.DB, address string, port int, selectors map[string]interfaces.ReviewerSelector, adminTokens map[string]string) *Server {
 	if address == "" {
 		address = "0.0.0.0"
 	}
@@ -36,11 +39,13 @@ func NewServer(logger *slog.Logger, db *gorm.DB, address string, port int) *Serv
 		port = 8080
 	}
 	return &Server{
-		userHandler:  NewUserHandler(logger, db),
-		teamHandler:  NewTeamHandler(logger, db),
-		prHandler:    NewPrHandler(logger, db),
-		statsHandler: NewStatsHandler(logger, db),
-		logger:       logger,
+		userHandler:         NewUserHandler(logger, db, selectors),
+		teamHandler:         NewTeamHandler(logger, db, selectors),
+		prHandler:           NewPrHandler(logger, db, selectors, adminTokens),
+		statsHandler:        NewStatsHandler(logger, db),
+		ownershipHandler:    NewOwnershipHandler(logger, db),
+		availabilityHandler: NewAvailabilityHandler(logger, db),
+		logger:              logger,
 
 		address: address,
 		port:    port,
@@ -98,18 +103,49 @@ func (s *Server) registerRoutes(router *chi.Mux) {
 	router.Route("/users", func(r chi.Router) {
 		r.Post("/setIsActive", s.userHandler.SetUserIsActive)
 		r.Get("/getReview", s.userHandler.GetUserReview)
+		r.Post("/setCapacity", s.userHandler.SetUserCapacity)
+		r.Post("/skills/set", s.userHandler.SetUserSkills)
+		r.Get("/skills/get", s.userHandler.GetUserSkills)
+
+		r.Route("/availability", func(r chi.Router) {
+			r.Post("/add", s.availabilityHandler.AddUnavailability)
+			r.Get("/list", s.availabilityHandler.ListUnavailability)
+			r.Post("/delete", s.availabilityHandler.DeleteUnavailability)
+		})
 	})
 
 	router.Route("/team", func(r chi.Router) {
 		r.Post("/add", s.teamHandler.CreateTeam)
 		r.Get("/get", s.teamHandler.GetTeam)
 		r.Post("/deactivate", s.teamHandler.MassDeactivateTeamUsers)
+		r.Get("/settings", s.teamHandler.GetTeamSettings)
+		r.Post("/settings", s.teamHandler.UpdateTeamSettings)
+		r.Post("/members/add", s.teamHandler.AddTeamMembers)
+		r.Post("/members/remove", s.teamHandler.RemoveTeamMember)
+		r.Post("/members/move", s.teamHandler.MoveTeamMember)
 	})
 
 	router.Route("/pullRequest", func(r chi.Router) {
 		r.Post("/create", s.prHandler.CreatePR)
+		r.Get("/get", s.prHandler.GetPR)
+		r.Get("/list", s.prHandler.ListPRs)
+		r.Get("/history", s.prHandler.GetPRHistory)
+		r.Get("/stale", s.prHandler.ListStaleReviews)
 		r.Post("/merge", s.prHandler.MergePR)
 		r.Post("/reassign", s.prHandler.ReassignPR)
+		r.Post("/review", s.prHandler.ReviewPR)
+		r.Post("/decline", s.prHandler.DeclinePR)
+		r.Post("/addReviewer", s.prHandler.AddReviewer)
+		r.Post("/removeReviewer", s.prHandler.RemoveReviewer)
+		r.Post("/markReady", s.prHandler.MarkReadyPR)
+		r.Post("/close", s.prHandler.ClosePR)
+		r.Post("/reopen", s.prHandler.ReopenPR)
+	})
+
+	router.Route("/ownership", func(r chi.Router) {
+		r.Post("/add", s.ownershipHandler.AddRule)
+		r.Get("/list", s.ownershipHandler.ListRules)
+		r.Post("/delete", s.ownershipHandler.DeleteRule)
 	})
 
 	router.Route("/statistics", func(r chi.Router) {
@@ -5,6 +5,7 @@ import (
 	"CodeRewievService/internal/interfaces"
 	"CodeRewievService/internal/services"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gorm.io/gorm"
 	"log/slog"
@@ -16,10 +17,10 @@ type TeamHandler struct {
 	logger      *slog.Logger
 }
 
-func NewTeamHandler(logger *slog.Logger, db *gorm.DB) *TeamHandler {
+func NewTeamHandler(logger *slog.Logger, db *gorm.DB, selectors map[string]interfaces.ReviewerSelector) *TeamHandler {
 	return &TeamHandler{
 		logger:      logger,
-		teamService: services.NewTeamService(db, logger),
+		teamService: services.NewTeamService(db, logger, selectors),
 	}
 }
 
@@ -45,10 +46,17 @@ func (handler *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = handler.teamService.Add(&entities.Team{
-		TeamName: requestBody.TeamName,
-		Members:  requestBody.Members,
+		TeamName:         requestBody.TeamName,
+		ReviewerStrategy: requestBody.ReviewerStrategy,
+		Members:          requestBody.Members,
 	})
 
+	if errors.Is(err, entities.ErrInvalidMembershipChange) {
+		handler.logger.Error(fmt.Sprintf("Failed to create team: %s", err))
+		handler.writeError(w, http.StatusBadRequest, "INVALID_MEMBERSHIP_CHANGE", err.Error())
+		return
+	}
+
 	if err != nil {
 		http.Error(w, "Failed to create team", http.StatusBadRequest)
 		handler.logger.Error(fmt.Sprintf("Failed to create team: %s", err))
@@ -76,16 +84,31 @@ func (handler *TeamHandler) MassDeactivateTeamUsers(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if err := handler.teamService.MassDeactivateTeamUsers(teamName); err != nil {
+	dryRun, err := dryRunRequested(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := handler.teamService.MassDeactivateTeamUsers(teamName, dryRun)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	message := "Team users deactivated successfully"
+	if dryRun {
+		message = "Dry run, no changes were saved"
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	err := json.NewEncoder(w).Encode(map[string]string{
-		"message": "Team users deactivated successfully",
-		"team":    teamName,
+	err = json.NewEncoder(w).Encode(entities.ResponseMassDeactivate{
+		Message:          message,
+		Team:             teamName,
+		DryRun:           dryRun,
+		DeactivatedUsers: report.DeactivatedUsers,
+		ReviewedPRs:      report.ReviewedPRs,
 	})
 	if err != nil {
 		return
@@ -120,3 +143,163 @@ func (handler *TeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (handler *TeamHandler) AddTeamMembers(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestAddTeamMembers
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	members, err := handler.teamService.AddMembers(requestBody.TeamName, requestBody.Members)
+	if handler.writeMembershipError(w, err) {
+		return
+	}
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to add team members: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseTeamMembers{
+		TeamName: requestBody.TeamName,
+		Members:  members,
+	})
+}
+
+func (handler *TeamHandler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestRemoveTeamMember
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	change, err := handler.teamService.RemoveMember(&requestBody)
+	if handler.writeMembershipError(w, err) {
+		return
+	}
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to remove team member: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseMembershipChange{
+		Membership: *change,
+	})
+}
+
+func (handler *TeamHandler) MoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestMoveTeamMember
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	change, err := handler.teamService.MoveMember(&requestBody)
+	if handler.writeMembershipError(w, err) {
+		return
+	}
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to move team member: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseMembershipChange{
+		Membership: *change,
+	})
+}
+
+// writeMembershipError reports why a team membership change was rejected.
+// It returns false when err is not a membership error.
+func (handler *TeamHandler) writeMembershipError(w http.ResponseWriter, err error) bool {
+	var statusCode int
+	var code string
+
+	switch {
+	case errors.Is(err, entities.ErrTeamNotFound), errors.Is(err, entities.ErrUserNotFound):
+		statusCode, code = http.StatusNotFound, "NOT_FOUND"
+	case errors.Is(err, entities.ErrNotTeamMember):
+		statusCode, code = http.StatusConflict, "NOT_TEAM_MEMBER"
+	case errors.Is(err, entities.ErrInvalidMembershipChange):
+		statusCode, code = http.StatusBadRequest, "INVALID_MEMBERSHIP_CHANGE"
+	default:
+		return false
+	}
+
+	handler.logger.Error(fmt.Sprintf("Team membership change rejected: %s", err))
+	handler.writeError(w, statusCode, code, err.Error())
+	return true
+}
+
+func (handler *TeamHandler) GetTeamSettings(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		handler.writeError(w, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+
+	settings, err := handler.teamService.GetSettings(teamName)
+	if errors.Is(err, entities.ErrTeamNotFound) {
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to get team settings: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseTeamSettings{
+		Settings: *settings,
+	})
+}
+
+func (handler *TeamHandler) UpdateTeamSettings(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestUpdateTeamSettings
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	settings, err := handler.teamService.UpdateSettings(&requestBody)
+	if errors.Is(err, entities.ErrTeamNotFound) {
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+	if errors.Is(err, entities.ErrInvalidTeamSettings) {
+		handler.logger.Error(fmt.Sprintf("Invalid team settings: %s", err))
+		handler.writeError(w, http.StatusBadRequest, "INVALID_SETTINGS", err.Error())
+		return
+	}
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to update team settings: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseTeamSettings{
+		Settings: *settings,
+	})
+}
+
+func (handler *TeamHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to encode response to json: %s", err))
+	}
+}
+
+func (handler *TeamHandler) writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	handler.writeJSON(w, statusCode, entities.Error{
+		Code:    code,
+		Message: message,
+	})
+}
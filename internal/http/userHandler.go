@@ -5,6 +5,7 @@ import (
 	"CodeRewievService/internal/interfaces"
 	"CodeRewievService/internal/services"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gorm.io/gorm"
 	"log/slog"
@@ -16,9 +17,9 @@ type UserHandler struct {
 	logger      *slog.Logger
 }
 
-func NewUserHandler(logger *slog.Logger, db *gorm.DB) *UserHandler {
+func NewUserHandler(logger *slog.Logger, db *gorm.DB, selectors map[string]interfaces.ReviewerSelector) *UserHandler {
 	return &UserHandler{
-		userService: services.NewUserService(db),
+		userService: services.NewUserService(db, selectors),
 		logger:      logger,
 	}
 }
@@ -31,16 +32,21 @@ func (handler *UserHandler) SetUserIsActive(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	newUser, err := handler.userService.SetIsActive(&entities.User{
+	if requestBody.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	newUser, report, err := handler.userService.SetIsActive(&entities.User{
 		UserID:   requestBody.UserID,
 		IsActive: requestBody.IsActive,
 	})
 
-	if err != nil {
-		handler.logger.Error(fmt.Sprintf("Error setting user isActive: %s", err))
+	if errors.Is(err, entities.ErrUserNotFound) {
+		handler.logger.Error(fmt.Sprintf("User not found: %s", requestBody.UserID))
 
-		w.WriteHeader(http.StatusBadRequest)
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
 		err = json.NewEncoder(w).Encode(entities.Error{
 			Code:    "NOT_FOUND",
 			Message: "resource not found",
@@ -48,10 +54,17 @@ func (handler *UserHandler) SetUserIsActive(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Error setting user isActive: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(entities.ResponseSetIsActive{
-		User: *newUser,
+		User:        *newUser,
+		ReviewedPRs: report.ReviewedPRs,
 	})
 	if err != nil {
 		handler.logger.Error(fmt.Sprintf("Error encoding response: %s", err))
@@ -80,3 +93,89 @@ func (handler *UserHandler) GetUserReview(w http.ResponseWriter, r *http.Request
 		return
 	}
 }
+
+func (handler *UserHandler) SetUserCapacity(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestSetCapacity
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	user, err := handler.userService.SetCapacity(requestBody.UserID, requestBody.MaxOpenReviews)
+
+	if errors.Is(err, entities.ErrInvalidCapacity) {
+		handler.writeError(w, http.StatusBadRequest, "INVALID_CAPACITY", "max_open_reviews cannot be negative")
+		return
+	}
+
+	if errors.Is(err, entities.ErrUserNotFound) {
+		handler.logger.Error(fmt.Sprintf("User not found: %s", requestBody.UserID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("Error setting user capacity: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseSetCapacity{
+		User: *user,
+	})
+}
+
+func (handler *UserHandler) SetUserSkills(w http.ResponseWriter, r *http.Request) {
+	var requestBody entities.RequestSetSkills
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		handler.logger.Error(fmt.Sprintf("Invalid request body: %s", err))
+		return
+	}
+
+	skills, err := handler.userService.SetSkills(requestBody.UserID, requestBody.Skills)
+	handler.writeSkills(w, requestBody.UserID, skills, err)
+}
+
+func (handler *UserHandler) GetUserSkills(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+
+	skills, err := handler.userService.GetSkills(userID)
+	handler.writeSkills(w, userID, skills, err)
+}
+
+func (handler *UserHandler) writeSkills(w http.ResponseWriter, userID string, skills []string, err error) {
+	if errors.Is(err, entities.ErrUserNotFound) {
+		handler.logger.Error(fmt.Sprintf("User not found: %s", userID))
+		handler.writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error(fmt.Sprintf("User skills error: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handler.writeJSON(w, http.StatusOK, entities.ResponseUserSkills{
+		UserID: userID,
+		Skills: skills,
+	})
+}
+
+func (handler *UserHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		handler.logger.Error(fmt.Sprintf("Failed to encode response to json: %s", err))
+	}
+}
+
+func (handler *UserHandler) writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	handler.writeJSON(w, statusCode, entities.Error{
+		Code:    code,
+		Message: message,
+	})
+}
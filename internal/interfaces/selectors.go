@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"CodeRewievService/internal/entities"
+
+	"gorm.io/gorm"
+)
+
+// ReviewerSelector picks up to count reviewers out of already filtered candidates.
+// Implementations run inside the caller's transaction.
+type ReviewerSelector interface {
+	Select(tx *gorm.DB, teamName string, candidates []entities.User, count int) ([]entities.User, error)
+}
@@ -3,20 +3,51 @@ package interfaces
 import "CodeRewievService/internal/entities"
 
 type UserServiceInterface interface {
-	SetIsActive(user *entities.User) (*entities.User, error)
+	SetIsActive(user *entities.User) (*entities.User, *entities.ReassignmentReport, error)
 	GetReview(userID string) (*entities.UserReview, error)
+	SetCapacity(userID string, maxOpenReviews *int) (*entities.User, error)
+	SetSkills(userID string, skills []string) ([]string, error)
+	GetSkills(userID string) ([]string, error)
 }
 
 type TeamServiceInterface interface {
 	Add(team *entities.Team) error
 	Get(teamName string) (*entities.Team, error)
-	MassDeactivateTeamUsers(teamName string) error
+	AddMembers(teamName string, members []entities.User) ([]entities.User, error)
+	RemoveMember(request *entities.RequestRemoveTeamMember) (*entities.MembershipChange, error)
+	MoveMember(request *entities.RequestMoveTeamMember) (*entities.MembershipChange, error)
+	MassDeactivateTeamUsers(teamName string, dryRun bool) (*entities.ReassignmentReport, error)
+	GetSettings(teamName string) (*entities.TeamSettings, error)
+	UpdateSettings(request *entities.RequestUpdateTeamSettings) (*entities.TeamSettings, error)
 }
 
 type PullRequestServiceInterface interface {
 	Create(PullRequest *entities.PullRequest) (*entities.PullRequest, error)
-	Reassign(prID string, userID string) (*entities.PullRequest, string, error)
-	Merge(prID string) (*entities.PullRequest, error)
+	Reassign(prID string, userID string, newReviewerID string, dryRun bool) (*entities.PullRequest, []string, error)
+	Merge(prID string, force bool, forcedBy string) (*entities.PullRequest, error)
+	Review(prID string, userID string, decision string, comment string) (*entities.PullRequest, error)
+	Decline(prID string, userID string, reasonCode string, comment string) (*entities.PullRequest, string, error)
+	AddReviewer(prID string, userID string) (*entities.PullRequest, error)
+	RemoveReviewer(prID string, userID string, refill bool) (*entities.PullRequest, string, error)
+	MarkReady(prID string) (*entities.PullRequest, error)
+	Close(prID string) (*entities.PullRequest, error)
+	Reopen(prID string) (*entities.PullRequest, error)
+	Get(prID string) (*entities.PullRequest, error)
+	History(prID string) ([]entities.ReviewerAssignmentEvent, error)
+	Stale(teamName string) ([]entities.StaleReview, error)
+	List(filter *entities.PullRequestFilter) ([]entities.PullRequest, string, error)
+}
+
+type AvailabilityServiceInterface interface {
+	AddUnavailability(period *entities.UserUnavailability) (*entities.UserUnavailability, error)
+	ListUnavailability(userID string) ([]entities.UserUnavailability, error)
+	DeleteUnavailability(id uint) error
+}
+
+type OwnershipServiceInterface interface {
+	AddRule(rule *entities.OwnershipRule) (*entities.OwnershipRule, error)
+	ListRules(teamName string) ([]entities.OwnershipRule, error)
+	DeleteRule(id uint) error
 }
 
 type StatsServiceInterface interface {
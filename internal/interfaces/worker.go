@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+type Worker interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context, timeout time.Duration) error
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const pendingAssignmentBatchSize = 100
+
+// AssignmentWorker periodically fills missing reviewer slots of pull requests
+// in the pending_assignments queue. Reviewers that became active, joined the
+// team or returned from unavailability are picked up on the next run.
+type AssignmentWorker struct {
+	db        *gorm.DB
+	logger    *slog.Logger
+	assigner  *reviewerAssigner
+	interval  time.Duration
+	mu        sync.Mutex
+	isRunning bool
+	stopCh    chan struct{}
+}
+
+func NewAssignmentWorker(db *gorm.DB, logger *slog.Logger, selectors map[string]interfaces.ReviewerSelector, interval time.Duration) *AssignmentWorker {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &AssignmentWorker{
+		db:       db,
+		logger:   logger,
+		assigner: newReviewerAssigner(selectors),
+		interval: interval,
+	}
+}
+
+func (w *AssignmentWorker) Start(ctx context.Context) error {
+	w.mu.Lock()
+	if w.isRunning {
+		w.mu.Unlock()
+		return fmt.Errorf("assignment worker is already running")
+	}
+	w.isRunning = true
+	w.stopCh = make(chan struct{})
+	stopCh := w.stopCh
+	w.mu.Unlock()
+
+	w.logger.Info("Starting assignment worker", "interval", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-stopCh:
+			return nil
+		case <-ticker.C:
+			if _, err := w.ProcessPending(); err != nil {
+				w.logger.Error(fmt.Sprintf("Failed to process pending assignments: %v", err))
+			}
+		}
+	}
+}
+
+func (w *AssignmentWorker) Stop(ctx context.Context, timeout time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.isRunning {
+		return nil
+	}
+
+	close(w.stopCh)
+	w.isRunning = false
+	w.logger.Info("Assignment worker stopped")
+	return nil
+}
+
+// ProcessPending tries to fill every queued pull request once and returns
+// the number of reviewers assigned.
+func (w *AssignmentWorker) ProcessPending() (int, error) {
+	var prIDs []string
+	err := w.db.Model(&entities.PendingAssignment{}).
+		Order("updated_at").
+		Limit(pendingAssignmentBatchSize).
+		Pluck("pull_request_id", &prIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	assigned := 0
+	for _, prID := range prIDs {
+		filled, err := w.fill(prID)
+		if err != nil {
+			w.logger.Error(fmt.Sprintf("Failed to fill reviewers for PR %s: %v", prID, err))
+			continue
+		}
+		if filled > 0 {
+			w.logger.Info("Assigned pending reviewers", "pull_request_id", prID, "count", filled)
+		}
+		assigned += filled
+	}
+
+	return assigned, nil
+}
+
+func (w *AssignmentWorker) fill(prID string) (int, error) {
+	filled := 0
+
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED lets concurrent workers pass over entries that are already being filled.
+		var pending entities.PendingAssignment
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("pull_request_id = ?", prID).
+			First(&pending)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		pr, err := lockPullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
+
+		missing := pr.RequiredReviewers - len(pr.AssignedReviewers)
+		if pr.Status == entities.PRStatusOpen && missing > 0 {
+			excluded := append([]string{pr.AuthorID}, reviewerUserIDs(pr.AssignedReviewers)...)
+			filled, err = w.assigner.fill(tx, pr, missing, excluded, reasonPendingFilled)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&entities.PendingAssignment{}).
+			Where("pull_request_id = ?", prID).
+			Update("updated_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return syncPendingAssignment(tx, prID)
+	})
+
+	return filled, err
+}
+
+// syncPendingAssignment queues an OPEN pull request that has fewer reviewers
+// than required and removes it from the queue otherwise.
+func syncPendingAssignment(tx *gorm.DB, prID string) error {
+	var pr entities.PullRequest
+	if err := tx.Preload("AssignedReviewers").Where("pull_request_id = ?", prID).First(&pr).Error; err != nil {
+		return err
+	}
+
+	if pr.Status == entities.PRStatusOpen && len(pr.AssignedReviewers) < pr.RequiredReviewers {
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&entities.PendingAssignment{PullRequestID: prID}).Error
+	}
+
+	return tx.Where("pull_request_id = ?", prID).Delete(&entities.PendingAssignment{}).Error
+}
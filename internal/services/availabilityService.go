@@ -0,0 +1,86 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type AvailabilityService struct {
+	db *gorm.DB
+}
+
+func NewAvailabilityService(db *gorm.DB) *AvailabilityService {
+	return &AvailabilityService{
+		db: db,
+	}
+}
+
+func (as *AvailabilityService) AddUnavailability(period *entities.UserUnavailability) (*entities.UserUnavailability, error) {
+	if period == nil {
+		return nil, errors.New("period cannot be nil")
+	}
+
+	if period.UserID == "" {
+		return nil, fmt.Errorf("%w: user_id cannot be empty", entities.ErrInvalidAvailability)
+	}
+
+	if period.StartsAt.IsZero() || period.EndsAt.IsZero() {
+		return nil, fmt.Errorf("%w: starts_at and ends_at are required", entities.ErrInvalidAvailability)
+	}
+
+	if !period.EndsAt.After(period.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", entities.ErrInvalidAvailability)
+	}
+
+	var user entities.User
+	result := as.db.Where("user_id = ?", period.UserID).First(&user)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrUserNotFound
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	newPeriod := &entities.UserUnavailability{
+		UserID:   period.UserID,
+		StartsAt: period.StartsAt,
+		EndsAt:   period.EndsAt,
+		Reason:   period.Reason,
+	}
+	if err := as.db.Create(newPeriod).Error; err != nil {
+		return nil, err
+	}
+
+	return newPeriod, nil
+}
+
+func (as *AvailabilityService) ListUnavailability(userID string) ([]entities.UserUnavailability, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("%w: user_id cannot be empty", entities.ErrInvalidAvailability)
+	}
+
+	periods := []entities.UserUnavailability{}
+	err := as.db.Where("user_id = ?", userID).
+		Order("starts_at").
+		Find(&periods).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return periods, nil
+}
+
+func (as *AvailabilityService) DeleteUnavailability(id uint) error {
+	result := as.db.Where("id = ?", id).Delete(&entities.UserUnavailability{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entities.ErrAvailabilityNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type OwnershipService struct {
+	db *gorm.DB
+}
+
+func NewOwnershipService(db *gorm.DB) *OwnershipService {
+	return &OwnershipService{
+		db: db,
+	}
+}
+
+func (ows *OwnershipService) AddRule(rule *entities.OwnershipRule) (*entities.OwnershipRule, error) {
+	if rule == nil {
+		return nil, errors.New("rule cannot be nil")
+	}
+
+	if rule.TeamName == "" {
+		return nil, fmt.Errorf("%w: team_name cannot be empty", entities.ErrInvalidOwnershipRule)
+	}
+
+	rule.Pattern = normalizeOwnershipPattern(rule.Pattern)
+	if rule.Pattern == "" {
+		return nil, fmt.Errorf("%w: pattern cannot be empty", entities.ErrInvalidOwnershipRule)
+	}
+	if _, err := compileOwnershipPattern(rule.Pattern); err != nil {
+		return nil, fmt.Errorf("%w: %s", entities.ErrInvalidOwnershipRule, err)
+	}
+
+	var team entities.Team
+	result := ows.db.Where("team_name = ?", rule.TeamName).First(&team)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrTeamNotFound
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	newRule := &entities.OwnershipRule{
+		Pattern:  rule.Pattern,
+		TeamName: rule.TeamName,
+	}
+	if err := ows.db.Create(newRule).Error; err != nil {
+		return nil, err
+	}
+
+	return newRule, nil
+}
+
+func (ows *OwnershipService) ListRules(teamName string) ([]entities.OwnershipRule, error) {
+	query := ows.db.Order("id")
+	if teamName != "" {
+		query = query.Where("team_name = ?", teamName)
+	}
+
+	rules := []entities.OwnershipRule{}
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (ows *OwnershipService) DeleteRule(id uint) error {
+	result := ows.db.Where("id = ?", id).Delete(&entities.OwnershipRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entities.ErrOwnershipRuleNotFound
+	}
+
+	return nil
+}
+
+// owningTeams returns the sorted names of teams whose ownership rules match at least one of paths.
+func owningTeams(tx *gorm.DB, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return []string{}, nil
+	}
+
+	var rules []entities.OwnershipRule
+	if err := tx.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]bool)
+	for _, rule := range rules {
+		if owners[rule.TeamName] {
+			continue
+		}
+
+		pattern, err := compileOwnershipPattern(rule.Pattern)
+		if err != nil {
+			continue
+		}
+
+		for _, path := range paths {
+			if pattern.MatchString(strings.TrimPrefix(path, "/")) {
+				owners[rule.TeamName] = true
+				break
+			}
+		}
+	}
+
+	teams := make([]string, 0, len(owners))
+	for teamName := range owners {
+		teams = append(teams, teamName)
+	}
+	sort.Strings(teams)
+
+	return teams, nil
+}
+
+func normalizeOwnershipPattern(pattern string) string {
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return pattern
+}
+
+// compileOwnershipPattern turns a glob into an anchored regexp.
+// "**" crosses directory boundaries, "*" and "?" do not.
+func compileOwnershipPattern(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
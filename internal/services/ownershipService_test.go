@@ -0,0 +1,179 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeOwnershipPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "services/billing/**", want: "services/billing/**"},
+		{pattern: "/services/billing/**", want: "services/billing/**"},
+		{pattern: "  **/*.sql  ", want: "**/*.sql"},
+		{pattern: "docs/", want: "docs/**"},
+		{pattern: "/docs/", want: "docs/**"},
+		{pattern: "main.go", want: "main.go"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeOwnershipPattern(tt.pattern); got != tt.want {
+			t.Errorf("normalizeOwnershipPattern(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestCompileOwnershipPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    bool
+	}{
+		{name: "double star matches file in dir", pattern: "services/billing/**", path: "services/billing/invoice.go", want: true},
+		{name: "double star matches nested file", pattern: "services/billing/**", path: "services/billing/api/v1/handler.go", want: true},
+		{name: "double star needs the prefix dir", pattern: "services/billing/**", path: "services/billingx/invoice.go", want: false},
+		{name: "double star is anchored", pattern: "services/billing/**", path: "old/services/billing/invoice.go", want: false},
+
+		{name: "leading double star matches root file", pattern: "**/*.sql", path: "init.sql", want: true},
+		{name: "leading double star matches nested file", pattern: "**/*.sql", path: "db/migrations/001.sql", want: true},
+		{name: "leading double star checks the extension", pattern: "**/*.sql", path: "db/init.sql.bak", want: false},
+		{name: "leading double star needs a name", pattern: "**/*.sql", path: "db/sql", want: false},
+
+		{name: "trailing slash matches dir contents", pattern: normalizeOwnershipPattern("docs/"), path: "docs/readme.md", want: true},
+		{name: "trailing slash matches nested contents", pattern: normalizeOwnershipPattern("docs/"), path: "docs/api/openapi.yml", want: true},
+		{name: "trailing slash does not match sibling", pattern: normalizeOwnershipPattern("docs/"), path: "docsite/index.md", want: false},
+
+		{name: "single star stays in dir", pattern: "cmd/*.go", path: "cmd/main.go", want: true},
+		{name: "single star does not cross dirs", pattern: "cmd/*.go", path: "cmd/tool/main.go", want: false},
+
+		{name: "question mark matches one char", pattern: "v?.go", path: "v1.go", want: true},
+		{name: "question mark needs a char", pattern: "v?.go", path: "v.go", want: false},
+		{name: "question mark matches only one char", pattern: "v?.go", path: "v10.go", want: false},
+		{name: "question mark does not match slash", pattern: "a?b", path: "a/b", want: false},
+
+		{name: "dot is literal", pattern: "main.go", path: "mainxgo", want: false},
+		{name: "metacharacters are literal", pattern: "a+b.(c)[d]{1}^$|\\.go", path: "a+b.(c)[d]{1}^$|\\.go", want: true},
+		{name: "plus is literal", pattern: "a+b.go", path: "aab.go", want: false},
+		{name: "parentheses are literal", pattern: "(c).go", path: "c.go", want: false},
+		{name: "brackets are literal", pattern: "[ab].go", path: "a.go", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compileOwnershipPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compileOwnershipPattern(%q) error: %v", tt.pattern, err)
+			}
+			if got := re.MatchString(tt.path); got != tt.want {
+				t.Errorf("pattern %q on %q = %v, want %v (regexp %s)", tt.pattern, tt.path, got, tt.want, re)
+			}
+		})
+	}
+}
+
+func TestCreateAssignsReviewersFromOwningTeams(t *testing.T) {
+	db := openTestDB(t)
+
+	mustCreate(t, db,
+		&[]entities.Team{
+			{TeamName: "web", MinReviewers: 2, MaxReviewers: 3},
+			{TeamName: "billing", MinReviewers: 1, MaxReviewers: 1},
+			{TeamName: "docs", MinReviewers: 1, MaxReviewers: 1},
+		},
+		&[]entities.User{
+			{UserID: "author", Username: "author", TeamName: "web", IsActive: true},
+			{UserID: "w1", Username: "w1", TeamName: "web", IsActive: true},
+			{UserID: "w2", Username: "w2", TeamName: "web", IsActive: true},
+			{UserID: "w3", Username: "w3", TeamName: "web", IsActive: true},
+			{UserID: "bl1", Username: "bl1", TeamName: "billing", IsActive: true},
+			{UserID: "d1", Username: "d1", TeamName: "docs", IsActive: true},
+		},
+		&[]entities.TeamMembership{
+			{TeamName: "web", UserID: "author", Role: entities.MembershipRoleMember},
+			{TeamName: "web", UserID: "w1", Role: entities.MembershipRoleMember},
+			{TeamName: "web", UserID: "w2", Role: entities.MembershipRoleMember},
+			{TeamName: "web", UserID: "w3", Role: entities.MembershipRoleMember},
+			{TeamName: "billing", UserID: "bl1", Role: entities.MembershipRoleMember},
+			{TeamName: "docs", UserID: "d1", Role: entities.MembershipRoleMember},
+		},
+	)
+
+	ownership := NewOwnershipService(db)
+	for _, rule := range []entities.OwnershipRule{
+		{Pattern: "services/billing/**", TeamName: "billing"},
+		{Pattern: "/docs/", TeamName: "docs"},
+	} {
+		if _, err := ownership.AddRule(&rule); err != nil {
+			t.Fatalf("AddRule(%q) error: %v", rule.Pattern, err)
+		}
+	}
+
+	service := NewPullRequestService(db, nil)
+	tests := []struct {
+		name         string
+		changedFiles []string
+		owners       []string
+		teamPicks    int
+	}{
+		{
+			name:         "owned and unowned files",
+			changedFiles: []string{"services/billing/invoice.go", "/docs/setup.md", "README.md"},
+			owners:       []string{"bl1", "d1"},
+			teamPicks:    1,
+		},
+		{
+			name:         "one owner",
+			changedFiles: []string{"services/billing/invoice.go"},
+			owners:       []string{"bl1"},
+			teamPicks:    2,
+		},
+		{
+			name:         "no owners",
+			changedFiles: []string{"web/index.html"},
+			owners:       []string{},
+			teamPicks:    3,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prID := fmt.Sprintf("pr-%d", i)
+			pr, err := service.Create(&entities.PullRequest{PullRequestID: prID, PullRequestName: prID,
+				AuthorID: "author", ChangedFiles: tt.changedFiles})
+			if err != nil {
+				t.Fatalf("Create() error: %v", err)
+			}
+
+			owners := []string{}
+			teamPicks := 0
+			for _, reviewer := range pr.AssignedReviewers {
+				switch reviewer.Source {
+				case entities.ReviewerSourceOwnership:
+					owners = append(owners, reviewer.UserID)
+				case entities.ReviewerSourceTeam:
+					if !strings.HasPrefix(reviewer.UserID, "w") {
+						t.Errorf("team pick %s is not from web", reviewer.UserID)
+					}
+					teamPicks++
+				default:
+					t.Errorf("reviewer %s has source %q", reviewer.UserID, reviewer.Source)
+				}
+			}
+			sort.Strings(owners)
+
+			if !slices.Equal(owners, tt.owners) {
+				t.Errorf("ownership picks = %v, want %v", owners, tt.owners)
+			}
+			if teamPicks != tt.teamPicks {
+				t.Errorf("team picks = %d, want %d", teamPicks, tt.teamPicks)
+			}
+		})
+	}
+}
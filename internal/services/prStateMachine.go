@@ -0,0 +1,38 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"fmt"
+	"slices"
+)
+
+const (
+	transitionMarkReady = "markReady"
+	transitionClose     = "close"
+	transitionReopen    = "reopen"
+	transitionMerge     = "merge"
+)
+
+type prTransition struct {
+	from []string
+	to   string
+}
+
+// prTransitions is the pull request state machine: every status change goes
+// through one of these actions. MERGED is final.
+var prTransitions = map[string]prTransition{
+	transitionMarkReady: {from: []string{entities.PRStatusDraft}, to: entities.PRStatusOpen},
+	transitionClose:     {from: []string{entities.PRStatusDraft, entities.PRStatusOpen}, to: entities.PRStatusClosed},
+	transitionReopen:    {from: []string{entities.PRStatusClosed}, to: entities.PRStatusOpen},
+	transitionMerge:     {from: []string{entities.PRStatusOpen}, to: entities.PRStatusMerged},
+}
+
+// checkTransition returns the status action leads to from status,
+// or ErrInvalidTransition when action is not allowed there.
+func checkTransition(action string, status string) (string, error) {
+	transition, ok := prTransitions[action]
+	if !ok || !slices.Contains(transition.from, status) {
+		return "", fmt.Errorf("%w: cannot %s PR in status %s", entities.ErrInvalidTransition, action, status)
+	}
+	return transition.to, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"errors"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		action  string
+		status  string
+		want    string
+		wantErr bool
+	}{
+		{action: transitionMarkReady, status: entities.PRStatusDraft, want: entities.PRStatusOpen},
+		{action: transitionMarkReady, status: entities.PRStatusOpen, wantErr: true},
+		{action: transitionMarkReady, status: entities.PRStatusClosed, wantErr: true},
+		{action: transitionMarkReady, status: entities.PRStatusMerged, wantErr: true},
+
+		{action: transitionClose, status: entities.PRStatusDraft, want: entities.PRStatusClosed},
+		{action: transitionClose, status: entities.PRStatusOpen, want: entities.PRStatusClosed},
+		{action: transitionClose, status: entities.PRStatusClosed, wantErr: true},
+		{action: transitionClose, status: entities.PRStatusMerged, wantErr: true},
+
+		{action: transitionReopen, status: entities.PRStatusClosed, want: entities.PRStatusOpen},
+		{action: transitionReopen, status: entities.PRStatusDraft, wantErr: true},
+		{action: transitionReopen, status: entities.PRStatusOpen, wantErr: true},
+		{action: transitionReopen, status: entities.PRStatusMerged, wantErr: true},
+
+		{action: transitionMerge, status: entities.PRStatusOpen, want: entities.PRStatusMerged},
+		{action: transitionMerge, status: entities.PRStatusDraft, wantErr: true},
+		{action: transitionMerge, status: entities.PRStatusClosed, wantErr: true},
+		{action: transitionMerge, status: entities.PRStatusMerged, wantErr: true},
+
+		{action: "approve", status: entities.PRStatusOpen, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := checkTransition(tt.action, tt.status)
+		if tt.wantErr {
+			if !errors.Is(err, entities.ErrInvalidTransition) {
+				t.Errorf("checkTransition(%q, %q) error = %v, want ErrInvalidTransition", tt.action, tt.status, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("checkTransition(%q, %q) unexpected error: %v", tt.action, tt.status, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("checkTransition(%q, %q) = %q, want %q", tt.action, tt.status, got, tt.want)
+		}
+	}
+}
+
+// A pull request goes through every status via PullRequestService, and every
+// action that the status or the merge policy forbids is rejected on the way.
+func TestPullRequestLifecycle(t *testing.T) {
+	db := openTestDB(t)
+
+	mustCreate(t, db,
+		&entities.Team{TeamName: "core", MinReviewers: 1, MaxReviewers: 1, MinApprovals: 1},
+		&[]entities.User{
+			{UserID: "author", Username: "author", TeamName: "core", IsActive: true},
+			{UserID: "r1", Username: "r1", TeamName: "core", IsActive: true},
+		},
+		&[]entities.TeamMembership{
+			{TeamName: "core", UserID: "author", Role: entities.MembershipRoleMember},
+			{TeamName: "core", UserID: "r1", Role: entities.MembershipRoleMember},
+		},
+	)
+
+	service := NewPullRequestService(db, nil)
+	pr, err := service.Create(&entities.PullRequest{PullRequestID: "pr-1", PullRequestName: "pr-1",
+		AuthorID: "author", Status: entities.PRStatusDraft})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+
+	expect := func(step string, pr *entities.PullRequest, err error, status string, reviewers int) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: error %v", step, err)
+		}
+		if pr.Status != status || len(pr.AssignedReviewers) != reviewers {
+			t.Fatalf("%s: status %s with %d reviewers, want %s with %d",
+				step, pr.Status, len(pr.AssignedReviewers), status, reviewers)
+		}
+	}
+	reject := func(step string, err error, want error) {
+		t.Helper()
+		if !errors.Is(err, want) {
+			t.Fatalf("%s: error %v, want %v", step, err, want)
+		}
+	}
+
+	expect("create draft", pr, nil, entities.PRStatusDraft, 0)
+
+	_, err = service.Merge("pr-1", false, "")
+	reject("merge draft", err, entities.ErrInvalidTransition)
+	_, err = service.Review("pr-1", "r1", entities.ReviewDecisionApproved, "")
+	reject("review draft", err, entities.ErrPRNotOpen)
+
+	pr, err = service.MarkReady("pr-1")
+	expect("mark ready", pr, err, entities.PRStatusOpen, 1)
+	_, err = service.MarkReady("pr-1")
+	reject("mark ready twice", err, entities.ErrInvalidTransition)
+
+	_, err = service.Merge("pr-1", false, "")
+	reject("merge without approval", err, entities.ErrMergeBlocked)
+
+	pr, err = service.Close("pr-1")
+	expect("close", pr, err, entities.PRStatusClosed, 1)
+	_, err = service.Merge("pr-1", false, "")
+	reject("merge closed", err, entities.ErrInvalidTransition)
+
+	pr, err = service.Reopen("pr-1")
+	expect("reopen", pr, err, entities.PRStatusOpen, 1)
+
+	_, err = service.Review("pr-1", "r1", entities.ReviewDecisionApproved, "")
+	if err != nil {
+		t.Fatalf("review: error %v", err)
+	}
+	pr, err = service.Merge("pr-1", false, "")
+	expect("merge", pr, err, entities.PRStatusMerged, 1)
+	if pr.MergedAt == nil {
+		t.Fatalf("merge: merged_at is not set")
+	}
+
+	pr, err = service.Merge("pr-1", false, "")
+	expect("merge twice", pr, err, entities.PRStatusMerged, 1)
+	_, err = service.Close("pr-1")
+	reject("close merged", err, entities.ErrInvalidTransition)
+	_, err = service.Reopen("pr-1")
+	reject("reopen merged", err, entities.ErrInvalidTransition)
+}
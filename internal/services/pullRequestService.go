@@ -2,18 +2,32 @@ package services
 
 import (
 	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
-	"math/rand"
+	"gorm.io/gorm/clause"
+	"slices"
+	"strings"
 	"time"
 )
 
+const (
+	defaultPullRequestPageSize = 50
+	maxPullRequestPageSize     = 100
+)
+
 type PullRequestService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	assigner *reviewerAssigner
 }
 
-func NewPullRequestService(db *gorm.DB) *PullRequestService {
-	return &PullRequestService{db: db}
+func NewPullRequestService(db *gorm.DB, selectors map[string]interfaces.ReviewerSelector) *PullRequestService {
+	return &PullRequestService{
+		db:       db,
+		assigner: newReviewerAssigner(selectors),
+	}
 }
 
 func (prs *PullRequestService) Create(pr *entities.PullRequest) (*entities.PullRequest, error) {
@@ -40,26 +54,36 @@ func (prs *PullRequestService) Create(pr *entities.PullRequest) (*entities.PullR
 	} else if result.Error != nil {
 		return nil, result.Error
 	}
+	if author.TeamName == "" {
+		return nil, fmt.Errorf("%w: %s is not in a team", entities.ErrAuthorNotFound, author.UserID)
+	}
 
-	var teamMembers []entities.User
-	err := prs.db.Where("team_name = ? AND user_id != ? AND is_active = ?", author.TeamName, pr.AuthorID, true).Find(&teamMembers).Error
-	if err != nil {
+	var authorTeam entities.Team
+	if err := prs.db.Where("team_name = ?", author.TeamName).First(&authorTeam).Error; err != nil {
 		return nil, err
 	}
 
-	// if len(teamMembers) == 0 {
-	// return nil, errors.New("no active team members available for review")
-	// }
-
-	assignedReviewers := selectRandomReviewers(teamMembers, 2, pr.PullRequestID)
-	println(len(assignedReviewers))
+	requested, err := prs.requestedReviewers(prs.db, pr)
+	if err != nil {
+		return nil, err
+	}
 
 	newPR := &entities.PullRequest{
-		PullRequestID:     pr.PullRequestID,
-		PullRequestName:   pr.PullRequestName,
-		AuthorID:          pr.AuthorID,
-		Status:            "OPEN",
-		AssignedReviewers: assignedReviewers,
+		PullRequestID:      pr.PullRequestID,
+		PullRequestName:    pr.PullRequestName,
+		AuthorID:           pr.AuthorID,
+		Status:             entities.PRStatusOpen,
+		RequiredReviewers:  authorTeam.MinReviewers,
+		ChangedFiles:       pr.ChangedFiles,
+		RequiredSkills:     pr.RequiredSkills,
+		RequestedReviewers: appendUserIDs([]string{}, requested),
+		ExcludedReviewers:  pr.ExcludedReviewers,
+	}
+
+	// Drafts get reviewers only when they are marked ready.
+	if pr.Status == entities.PRStatusDraft {
+		newPR.Status = entities.PRStatusDraft
+		newPR.RequiredReviewers = 0
 	}
 
 	err = prs.db.Transaction(func(tx *gorm.DB) error {
@@ -67,7 +91,13 @@ func (prs *PullRequestService) Create(pr *entities.PullRequest) (*entities.PullR
 			return err
 		}
 
-		return nil
+		if newPR.Status != entities.PRStatusOpen {
+			return nil
+		}
+
+		uncovered, err := prs.assignInitial(tx, newPR, author, authorTeam, requested, reasonCreated)
+		newPR.UncoveredSkills = uncovered
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -81,156 +111,830 @@ func (prs *PullRequestService) Create(pr *entities.PullRequest) (*entities.PullR
 	if err != nil {
 		return nil, err
 	}
+	createdPR.UncoveredSkills = newPR.UncoveredSkills
 
 	return &createdPR, nil
 }
 
-func (prs *PullRequestService) Merge(prID string) (*entities.PullRequest, error) {
-	if prID == "" {
-		return nil, errors.New("pull_request_id cannot be empty")
+// assignInitial picks and stores the first reviewers of pr, which must already be OPEN,
+// and returns the required skills nobody could cover.
+func (prs *PullRequestService) assignInitial(tx *gorm.DB, pr *entities.PullRequest, author entities.User, authorTeam entities.Team, requested []entities.User, reason string) ([]string, error) {
+	reviewers, uncovered, err := prs.assigner.selectInitial(tx, reviewerRequest{
+		pullRequestID:  pr.PullRequestID,
+		author:         author,
+		authorTeam:     authorTeam,
+		changedFiles:   pr.ChangedFiles,
+		requiredSkills: pr.RequiredSkills,
+		requested:      requested,
+		excluded:       pr.ExcludedReviewers,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var pr entities.PullRequest
-	result := prs.db.Where("pull_request_id = ?", prID).First(&pr)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, entities.ErrNotFound
-	} else if result.Error != nil {
-		return nil, result.Error
+	if len(reviewers) > 0 {
+		if err := tx.Create(&reviewers).Error; err != nil {
+			return nil, err
+		}
 	}
 
-	if pr.Status == "MERGED" {
-		return &pr, nil
+	if err := recordEvents(tx, reviewerEvents(reviewers, entities.AssignmentEventAssigned, reason)); err != nil {
+		return nil, err
 	}
 
-	now := time.Now()
-	pr.Status = "MERGED"
-	pr.MergedAt = &now
+	return uncovered, syncPendingAssignment(tx, pr.PullRequestID)
+}
 
-	if err := prs.db.Save(&pr).Error; err != nil {
-		return nil, err
+// requestedReviewers loads the reviewers explicitly requested by the author
+// and checks that each of them can review the pull request right now: active,
+// available and below their open review limit. Reviewers of a draft are only
+// checked for being active, as they are assigned when it is marked ready.
+func (prs *PullRequestService) requestedReviewers(tx *gorm.DB, pr *entities.PullRequest) ([]entities.User, error) {
+	excluded := make(map[string]bool, len(pr.ExcludedReviewers))
+	for _, userID := range pr.ExcludedReviewers {
+		excluded[userID] = true
 	}
 
-	return &pr, nil
+	seen := make(map[string]bool, len(pr.RequestedReviewers))
+	requested := []entities.User{}
+	for _, userID := range pr.RequestedReviewers {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		if excluded[userID] {
+			return nil, fmt.Errorf("%w: %s", entities.ErrReviewerExcluded, userID)
+		}
+
+		check := eligibleReviewer
+		if pr.Status == entities.PRStatusDraft {
+			check = explicitReviewer
+		}
+		user, err := check(tx, userID, pr.AuthorID)
+		if err != nil {
+			return nil, err
+		}
+
+		requested = append(requested, *user)
+	}
+
+	return requested, nil
 }
 
-func selectRandomReviewers(users []entities.User, max int, pullRequestID string) []entities.PullRequestReviewer {
-	if len(users) == 0 {
-		return []entities.PullRequestReviewer{}
+// Merge merges the pull request if the merge policy of the author's team is
+// satisfied. With force set it merges past the policy on behalf of forcedBy,
+// the admin the caller authenticated as, and records it in merge_overrides.
+func (prs *PullRequestService) Merge(prID string, force bool, forcedBy string) (*entities.PullRequest, error) {
+	if prID == "" {
+		return nil, errors.New("pull_request_id cannot be empty")
 	}
 
-	if len(users) <= max {
-		max = len(users)
+	if force && forcedBy == "" {
+		return nil, fmt.Errorf("%w: no admin given", entities.ErrMergeOverrideForbidden)
 	}
 
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	shuffled := make([]entities.User, len(users))
-	copy(shuffled, users)
-	r.Shuffle(len(shuffled), func(i, j int) {
-		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	var merged *entities.PullRequest
+	err := prs.db.Transaction(func(tx *gorm.DB) error {
+		pr, err := lockPullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
+		merged = pr
+
+		if pr.Status == entities.PRStatusMerged {
+			return nil
+		}
+		status, err := checkTransition(transitionMerge, pr.Status)
+		if err != nil {
+			return err
+		}
+
+		// An author who left their team has no merge policy to apply.
+		var team entities.Team
+		if pr.Author.TeamName != "" {
+			if err := tx.Where("team_name = ?", pr.Author.TeamName).First(&team).Error; err != nil {
+				return err
+			}
+		}
+
+		unmet := mergeBlockers(&team, pr)
+		if len(unmet) > 0 && !force {
+			return fmt.Errorf("%w: %s", entities.ErrMergeBlocked, strings.Join(unmet, "; "))
+		}
+
+		if force {
+			override := entities.MergeOverride{
+				PullRequestID:   prID,
+				ForcedBy:        forcedBy,
+				UnmetConditions: strings.Join(unmet, "; "),
+			}
+			if err := tx.Create(&override).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		pr.Status = status
+		pr.MergedAt = &now
+
+		if err := tx.Omit(clause.Associations).Save(pr).Error; err != nil {
+			return err
+		}
+
+		return syncPendingAssignment(tx, prID)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
 
-	reviewers := make([]entities.PullRequestReviewer, max)
-	for i := 0; i < max; i++ {
-		reviewers[i] = entities.PullRequestReviewer{
-			PullRequestID: pullRequestID,
-			UserID:        shuffled[i].UserID,
-			AssignedAt:    time.Now(),
+// mergeBlockers lists the conditions of the team merge policy that pr does not meet.
+func mergeBlockers(team *entities.Team, pr *entities.PullRequest) []string {
+	var unmet []string
+
+	approvals := 0
+	var changesRequestedBy []string
+	decisions := make(map[string]string, len(pr.AssignedReviewers))
+	for _, reviewer := range pr.AssignedReviewers {
+		decisions[reviewer.UserID] = reviewer.Decision
+		switch reviewer.Decision {
+		case entities.ReviewDecisionApproved:
+			approvals++
+		case entities.ReviewDecisionChangesRequested:
+			changesRequestedBy = append(changesRequestedBy, reviewer.UserID)
 		}
 	}
 
-	return reviewers
+	if approvals < team.MinApprovals {
+		unmet = append(unmet, fmt.Sprintf("%d of %d required approvals", approvals, team.MinApprovals))
+	}
+	if team.BlockOnChangesRequested && len(changesRequestedBy) > 0 {
+		unmet = append(unmet, fmt.Sprintf("changes requested by %s", strings.Join(changesRequestedBy, ", ")))
+	}
+	if team.RequireLeadApproval && team.LeadUserID != nil &&
+		decisions[*team.LeadUserID] != entities.ReviewDecisionApproved {
+		unmet = append(unmet, fmt.Sprintf("approval from team lead %s", *team.LeadUserID))
+	}
+
+	return unmet
 }
 
-func (prs *PullRequestService) Reassign(prID string, oldUserID string) (*entities.PullRequest, string, error) {
+// Reassign replaces oldUserID on the pull request and returns it with the
+// reviewers that were added. With dryRun set nothing is saved and the result
+// shows what the reassignment would do.
+func (prs *PullRequestService) Reassign(prID string, oldUserID string, newReviewerID string, dryRun bool) (*entities.PullRequest, []string, error) {
 	if prID == "" {
-		return nil, "", errors.New("pull_request_id cannot be empty")
+		return nil, nil, errors.New("pull_request_id cannot be empty")
 	}
 	if oldUserID == "" {
-		return nil, "", errors.New("old_user_id cannot be empty")
+		return nil, nil, errors.New("old_user_id cannot be empty")
 	}
 
-	var pr entities.PullRequest
-	result := prs.db.Preload("AssignedReviewers").
-		Where("pull_request_id = ?", prID).
-		First(&pr)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, "", entities.ErrNotFound
-	} else if result.Error != nil {
-		return nil, "", result.Error
+	var updatedPR entities.PullRequest
+	var added []string
+	err := runTransaction(prs.db, dryRun, func(tx *gorm.DB) error {
+		pr, err := lockEditablePullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
+
+		reviewers, err := prs.assigner.replace(tx, pr, oldUserID, newReviewerID, reasonReassigned)
+		if err != nil {
+			return err
+		}
+		added = reviewerUserIDs(reviewers)
+
+		if err := syncPendingAssignment(tx, prID); err != nil {
+			return err
+		}
+
+		return tx.Preload("AssignedReviewers.User").
+			Preload("Author").
+			Where("pull_request_id = ?", prID).
+			First(&updatedPR).Error
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if pr.Status == "MERGED" {
-		return nil, "", entities.ErrPRAlreadyMerged
+	return &updatedPR, added, nil
+}
+
+func (prs *PullRequestService) Review(prID string, userID string, decision string, comment string) (*entities.PullRequest, error) {
+	if prID == "" {
+		return nil, errors.New("pull_request_id cannot be empty")
+	}
+	if userID == "" {
+		return nil, errors.New("user_id cannot be empty")
+	}
+	if decision != entities.ReviewDecisionApproved && decision != entities.ReviewDecisionChangesRequested {
+		return nil, fmt.Errorf("%w: %q", entities.ErrInvalidDecision, decision)
 	}
 
-	found := false
-	for _, reviewer := range pr.AssignedReviewers {
-		if reviewer.UserID == oldUserID {
-			found = true
-			break
+	err := prs.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockEditablePullRequest(tx, prID); err != nil {
+			return err
 		}
+
+		now := time.Now()
+		result := tx.Model(&entities.PullRequestReviewer{}).
+			Where("pull_request_id = ? AND user_id = ?", prID, userID).
+			Updates(map[string]interface{}{
+				"decision":   decision,
+				"comment":    comment,
+				"decided_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entities.ErrUserIsNotAssignedToPR
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reviewedPR entities.PullRequest
+	err = prs.db.Preload("AssignedReviewers").
+		Where("pull_request_id = ?", prID).
+		First(&reviewedPR).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &reviewedPR, nil
+}
+
+// Decline removes userID from the reviewers of the pull request and picks a
+// replacement the same way Reassign does. Without an eligible replacement the
+// reviewer is still removed and the pull request waits for the pending queue.
+// The decline is recorded so the reviewer is not picked for the same author
+// again within declineCooldown.
+func (prs *PullRequestService) Decline(prID string, userID string, reasonCode string, comment string) (*entities.PullRequest, string, error) {
+	if prID == "" {
+		return nil, "", errors.New("pull_request_id cannot be empty")
 	}
-	if !found {
-		return nil, "", entities.ErrUserIsNotAssignedToPR
+	if userID == "" {
+		return nil, "", errors.New("user_id cannot be empty")
 	}
 
-	var oldReviewer entities.User
-	result = prs.db.Where("user_id = ?", oldUserID).First(&oldReviewer)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, "", entities.ErrNotFound
-	} else if result.Error != nil {
-		return nil, "", result.Error
+	switch reasonCode {
+	case entities.DeclineReasonConflictOfInterest, entities.DeclineReasonNoContext, entities.DeclineReasonOverloaded:
+	default:
+		return nil, "", fmt.Errorf("%w: %q", entities.ErrInvalidDeclineReason, reasonCode)
 	}
 
-	var availableReviewers []entities.User
-	query := prs.db.Where("team_name = ? AND user_id != ? AND is_active = ?",
-		oldReviewer.TeamName, pr.AuthorID, true)
+	var replacedBy string
+	err := prs.db.Transaction(func(tx *gorm.DB) error {
+		pr, err := lockEditablePullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
 
-	for _, reviewer := range pr.AssignedReviewers {
-		query = query.Where("user_id != ?", reviewer.UserID)
+		if !slices.Contains(reviewerUserIDs(pr.AssignedReviewers), userID) {
+			return entities.ErrUserIsNotAssignedToPR
+		}
+
+		decline := entities.ReviewDecline{
+			PullRequestID: prID,
+			UserID:        userID,
+			AuthorID:      pr.AuthorID,
+			ReasonCode:    reasonCode,
+			Comment:       comment,
+		}
+		if err := tx.Create(&decline).Error; err != nil {
+			return err
+		}
+
+		err = recordEvents(tx, []entities.ReviewerAssignmentEvent{{
+			PullRequestID: prID,
+			EventType:     entities.AssignmentEventDeclined,
+			UserID:        userID,
+			Reason:        reasonCode,
+		}})
+		if err != nil {
+			return err
+		}
+
+		replacedBy, err = prs.assigner.unassign(tx, pr, userID, true, entities.AssignmentEventUnassigned, reasonDeclined)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
-	err := query.Find(&availableReviewers).Error
+	pr, err := prs.reload(prID, nil)
 	if err != nil {
 		return nil, "", err
 	}
 
-	if len(availableReviewers) == 0 {
-		return nil, "", entities.ErrNoReplacement
+	return pr, replacedBy, nil
+}
+
+// AddReviewer assigns one more reviewer to an open pull request. The reviewer
+// must be active, available and below their open review limit.
+func (prs *PullRequestService) AddReviewer(prID string, userID string) (*entities.PullRequest, error) {
+	if prID == "" {
+		return nil, errors.New("pull_request_id cannot be empty")
+	}
+	if userID == "" {
+		return nil, errors.New("user_id cannot be empty")
 	}
 
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	newReviewer := availableReviewers[r.Intn(len(availableReviewers))]
+	err := prs.db.Transaction(func(tx *gorm.DB) error {
+		pr, err := lockEditablePullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
 
-	err = prs.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("pull_request_id = ? AND user_id = ?", prID, oldUserID).
-			Delete(&entities.PullRequestReviewer{}).Error; err != nil {
+		if slices.Contains(reviewerUserIDs(pr.AssignedReviewers), userID) {
+			return fmt.Errorf("%w: %s", entities.ErrReviewerAlreadyAssigned, userID)
+		}
+
+		user, err := eligibleReviewer(tx, userID, pr.AuthorID)
+		if err != nil {
 			return err
 		}
 
-		newPRReviewer := entities.PullRequestReviewer{
-			PullRequestID: prID,
-			UserID:        newReviewer.UserID,
-			AssignedAt:    time.Now(),
+		added := toPullRequestReviewers(prID, []entities.User{*user}, entities.ReviewerSourceRequested)
+		if err := tx.Create(&added).Error; err != nil {
+			return err
 		}
-		if err := tx.Create(&newPRReviewer).Error; err != nil {
+		if err := recordEvents(tx, reviewerEvents(added, entities.AssignmentEventAssigned, reasonAddedManually)); err != nil {
 			return err
 		}
 
-		return nil
+		return syncPendingAssignment(tx, prID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prs.reload(prID, nil)
+}
+
+// RemoveReviewer removes a reviewer from an open pull request. With refill set
+// a replacement is picked the same way Reassign does; otherwise, or when nobody
+// is eligible, missing slots are left to the pending queue.
+func (prs *PullRequestService) RemoveReviewer(prID string, userID string, refill bool) (*entities.PullRequest, string, error) {
+	if prID == "" {
+		return nil, "", errors.New("pull_request_id cannot be empty")
+	}
+	if userID == "" {
+		return nil, "", errors.New("user_id cannot be empty")
+	}
+
+	var replacedBy string
+	err := prs.db.Transaction(func(tx *gorm.DB) error {
+		pr, err := lockEditablePullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(reviewerUserIDs(pr.AssignedReviewers), userID) {
+			return entities.ErrUserIsNotAssignedToPR
+		}
+
+		replacedBy, err = prs.assigner.unassign(tx, pr, userID, refill, entities.AssignmentEventUnassigned, reasonRemovedManually)
+		return err
 	})
 	if err != nil {
 		return nil, "", err
 	}
 
-	var updatedPR entities.PullRequest
-	err = prs.db.Preload("AssignedReviewers.User").
-		Preload("Author").
+	pr, err := prs.reload(prID, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return pr, replacedBy, nil
+}
+
+// MarkReady moves a draft to OPEN and assigns its reviewers. Requested reviewers
+// who can no longer review it are skipped.
+func (prs *PullRequestService) MarkReady(prID string) (*entities.PullRequest, error) {
+	if prID == "" {
+		return nil, errors.New("pull_request_id cannot be empty")
+	}
+
+	var uncovered []string
+	err := prs.db.Transaction(func(tx *gorm.DB) error {
+		pr, err := lockPullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
+
+		status, err := checkTransition(transitionMarkReady, pr.Status)
+		if err != nil {
+			return err
+		}
+
+		uncovered, err = prs.open(tx, pr, status, reasonMarkedReady)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prs.reload(prID, uncovered)
+}
+
+// Close closes a draft or an open pull request without merging it.
+// Its reviewers are kept so that reopening can restore them.
+func (prs *PullRequestService) Close(prID string) (*entities.PullRequest, error) {
+	if prID == "" {
+		return nil, errors.New("pull_request_id cannot be empty")
+	}
+
+	err := prs.db.Transaction(func(tx *gorm.DB) error {
+		pr, err := lockPullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
+
+		status, err := checkTransition(transitionClose, pr.Status)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&entities.PullRequest{}).
+			Where("pull_request_id = ?", prID).
+			Updates(map[string]interface{}{
+				"status":    status,
+				"closed_at": time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return syncPendingAssignment(tx, prID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prs.reload(prID, nil)
+}
+
+// Reopen moves a closed pull request back to OPEN with the current
+// min_reviewers of the author's team. Previous reviewers who are still
+// eligible are restored and the remaining slots are filled as usual.
+// A pull request closed before it had any reviewers is assigned from scratch.
+func (prs *PullRequestService) Reopen(prID string) (*entities.PullRequest, error) {
+	if prID == "" {
+		return nil, errors.New("pull_request_id cannot be empty")
+	}
+
+	var uncovered []string
+	err := prs.db.Transaction(func(tx *gorm.DB) error {
+		pr, err := lockPullRequest(tx, prID)
+		if err != nil {
+			return err
+		}
+
+		status, err := checkTransition(transitionReopen, pr.Status)
+		if err != nil {
+			return err
+		}
+
+		if len(pr.AssignedReviewers) == 0 {
+			uncovered, err = prs.open(tx, pr, status, reasonReopened)
+			return err
+		}
+
+		// The team may have changed its settings while the PR was closed.
+		authorTeam, err := authorTeamOf(tx, pr)
+		if err != nil {
+			return err
+		}
+		pr.RequiredReviewers = authorTeam.MinReviewers
+
+		// Eligibility is checked before the status changes, so this PR does not
+		// count towards the review limits of its own reviewers.
+		if err := prs.restoreReviewers(tx, pr); err != nil {
+			return err
+		}
+
+		err = tx.Model(&entities.PullRequest{}).
+			Where("pull_request_id = ?", prID).
+			Updates(map[string]interface{}{
+				"status":             status,
+				"required_reviewers": pr.RequiredReviewers,
+				"closed_at":          nil,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return syncPendingAssignment(tx, prID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prs.reload(prID, uncovered)
+}
+
+// open sets pr to status with the current min_reviewers of the author's team
+// and runs the initial reviewer selection.
+func (prs *PullRequestService) open(tx *gorm.DB, pr *entities.PullRequest, status string, reason string) ([]string, error) {
+	authorTeam, err := authorTeamOf(tx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := []entities.User{}
+	for _, userID := range pr.RequestedReviewers {
+		user, err := eligibleReviewer(tx, userID, pr.AuthorID)
+		if errors.Is(err, entities.ErrReviewerNotFound) || errors.Is(err, entities.ErrReviewerInactive) ||
+			errors.Is(err, entities.ErrReviewerNotEligible) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		requested = append(requested, *user)
+	}
+
+	err = tx.Model(&entities.PullRequest{}).
+		Where("pull_request_id = ?", pr.PullRequestID).
+		Updates(map[string]interface{}{
+			"status":             status,
+			"required_reviewers": authorTeam.MinReviewers,
+			"closed_at":          nil,
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return prs.assignInitial(tx, pr, pr.Author, *authorTeam, requested, reason)
+}
+
+// authorTeamOf loads the current settings of the author's team of pr.
+func authorTeamOf(tx *gorm.DB, pr *entities.PullRequest) (*entities.Team, error) {
+	if pr.Author.TeamName == "" {
+		return nil, fmt.Errorf("%w: %s is not in a team", entities.ErrAuthorNotFound, pr.AuthorID)
+	}
+
+	var team entities.Team
+	if err := tx.Where("team_name = ?", pr.Author.TeamName).First(&team).Error; err != nil {
+		return nil, err
+	}
+
+	return &team, nil
+}
+
+// restoreReviewers removes the reviewers of pr who are no longer eligible
+// and fills the freed slots.
+func (prs *PullRequestService) restoreReviewers(tx *gorm.DB, pr *entities.PullRequest) error {
+	previous := reviewerUserIDs(pr.AssignedReviewers)
+
+	var eligible []string
+	if err := eligibleReviewers(tx).Where("user_id IN ?", previous).Pluck("user_id", &eligible).Error; err != nil {
+		return err
+	}
+
+	var dropped []string
+	var droppedReviewers []entities.PullRequestReviewer
+	for _, reviewer := range pr.AssignedReviewers {
+		if !slices.Contains(eligible, reviewer.UserID) {
+			dropped = append(dropped, reviewer.UserID)
+			droppedReviewers = append(droppedReviewers, reviewer)
+		}
+	}
+
+	if len(dropped) > 0 {
+		if err := tx.Where("pull_request_id = ? AND user_id IN ?", pr.PullRequestID, dropped).
+			Delete(&entities.PullRequestReviewer{}).Error; err != nil {
+			return err
+		}
+		if err := recordEvents(tx, reviewerEvents(droppedReviewers, entities.AssignmentEventUnassigned, reasonNotEligible)); err != nil {
+			return err
+		}
+	}
+
+	excluded := append([]string{pr.AuthorID}, previous...)
+	_, err := prs.assigner.fill(tx, pr, pr.RequiredReviewers-len(eligible), excluded, reasonReopened)
+	return err
+}
+
+func (prs *PullRequestService) reload(prID string, uncovered []string) (*entities.PullRequest, error) {
+	var pr entities.PullRequest
+	err := prs.db.Preload("AssignedReviewers").
 		Where("pull_request_id = ?", prID).
-		First(&updatedPR).Error
+		First(&pr).Error
+	if err != nil {
+		return nil, err
+	}
+	pr.UncoveredSkills = uncovered
+
+	return &pr, nil
+}
+
+func (prs *PullRequestService) Get(prID string) (*entities.PullRequest, error) {
+	if prID == "" {
+		return nil, errors.New("pull_request_id cannot be empty")
+	}
+
+	var pr entities.PullRequest
+	result := prs.db.Preload("AssignedReviewers").Where("pull_request_id = ?", prID).First(&pr)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrNotFound
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &pr, nil
+}
+
+// List returns one page of pull requests matching filter ordered by creation
+// time, and the cursor of the next page or "" when this page is the last.
+func (prs *PullRequestService) List(filter *entities.PullRequestFilter) ([]entities.PullRequest, string, error) {
+	if filter == nil {
+		filter = &entities.PullRequestFilter{}
+	}
+
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultPullRequestPageSize
+	}
+	if limit < 0 || limit > maxPullRequestPageSize {
+		return nil, "", fmt.Errorf("%w: limit must be between 1 and %d", entities.ErrInvalidFilter, maxPullRequestPageSize)
+	}
+
+	order := "DESC"
+	comparison := "<"
+	switch filter.Sort {
+	case "", "desc":
+	case "asc":
+		order, comparison = "ASC", ">"
+	default:
+		return nil, "", fmt.Errorf("%w: sort must be asc or desc", entities.ErrInvalidFilter)
+	}
+
+	query := prs.db.Model(&entities.PullRequest{}).Preload("AssignedReviewers")
+
+	if filter.Status != "" {
+		if !isPRStatus(filter.Status) {
+			return nil, "", fmt.Errorf("%w: unknown status %q", entities.ErrInvalidFilter, filter.Status)
+		}
+		query = query.Where("pull_requests.status = ?", filter.Status)
+	}
+	if filter.AuthorID != "" {
+		query = query.Where("pull_requests.author_id = ?", filter.AuthorID)
+	}
+	if filter.ReviewerID != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM pull_request_reviewers prr "+
+			"WHERE prr.pull_request_id = pull_requests.pull_request_id AND prr.user_id = ?)", filter.ReviewerID)
+	}
+	if filter.TeamName != "" {
+		query = query.Where("pull_requests.author_id IN (SELECT user_id FROM users WHERE team_name = ?)", filter.TeamName)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("pull_requests.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("pull_requests.created_at < ?", *filter.CreatedTo)
+	}
+	if filter.MergedFrom != nil {
+		query = query.Where("pull_requests.merged_at >= ?", *filter.MergedFrom)
+	}
+	if filter.MergedTo != nil {
+		query = query.Where("pull_requests.merged_at < ?", *filter.MergedTo)
+	}
+
+	if filter.Cursor != "" {
+		createdAt, prID, err := decodePullRequestCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Where("(pull_requests.created_at, pull_requests.pull_request_id) "+comparison+" (?, ?)", createdAt, prID)
+	}
+
+	// One extra row tells whether there is a next page.
+	var page []entities.PullRequest
+	err := query.Order("pull_requests.created_at " + order).
+		Order("pull_requests.pull_request_id " + order).
+		Limit(limit + 1).
+		Find(&page).Error
 	if err != nil {
 		return nil, "", err
 	}
 
-	return &updatedPR, newReviewer.UserID, nil
+	nextCursor := ""
+	if len(page) > limit {
+		page = page[:limit]
+		last := page[len(page)-1]
+		nextCursor = encodePullRequestCursor(last.CreatedAt, last.PullRequestID)
+	}
+
+	return page, nextCursor, nil
+}
+
+func isPRStatus(status string) bool {
+	switch status {
+	case entities.PRStatusDraft, entities.PRStatusOpen, entities.PRStatusClosed, entities.PRStatusMerged:
+		return true
+	}
+	return false
+}
+
+// The cursor is the creation time and id of the last pull request on the page.
+func encodePullRequestCursor(createdAt time.Time, prID string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + prID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePullRequestCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: malformed cursor", entities.ErrInvalidFilter)
+	}
+
+	createdAt, prID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("%w: malformed cursor", entities.ErrInvalidFilter)
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: malformed cursor", entities.ErrInvalidFilter)
+	}
+
+	return parsed, prID, nil
+}
+
+// History returns the reviewer assignment events of a pull request, oldest first.
+func (prs *PullRequestService) History(prID string) ([]entities.ReviewerAssignmentEvent, error) {
+	if _, err := prs.Get(prID); err != nil {
+		return nil, err
+	}
+
+	events := []entities.ReviewerAssignmentEvent{}
+	err := prs.db.Where("pull_request_id = ?", prID).
+		Order("created_at").
+		Order("id").
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Stale lists the pending reviews of OPEN pull requests that are past the review
+// SLA of the author's team. An empty teamName lists all teams.
+func (prs *PullRequestService) Stale(teamName string) ([]entities.StaleReview, error) {
+	query := staleReviewsQuery(prs.db, time.Now())
+	if teamName != "" {
+		query = query.Where("t.team_name = ?", teamName)
+	}
+
+	stale := []entities.StaleReview{}
+	if err := query.Scan(&stale).Error; err != nil {
+		return nil, err
+	}
+
+	return stale, nil
+}
+
+// lockEditablePullRequest locks a pull request whose reviewers may be changed,
+// which is only the case while it is OPEN.
+func lockEditablePullRequest(tx *gorm.DB, prID string) (*entities.PullRequest, error) {
+	pr, err := lockPullRequest(tx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr.Status == entities.PRStatusMerged {
+		return nil, entities.ErrPRAlreadyMerged
+	}
+	if pr.Status != entities.PRStatusOpen {
+		return nil, fmt.Errorf("%w: %s", entities.ErrPRNotOpen, pr.Status)
+	}
+
+	return pr, nil
+}
+
+// lockPullRequest loads a pull request with its reviewers and author and locks
+// its row until the end of the transaction.
+func lockPullRequest(tx *gorm.DB, prID string) (*entities.PullRequest, error) {
+	var pr entities.PullRequest
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("AssignedReviewers").
+		Preload("Author").
+		Where("pull_request_id = ?", prID).
+		First(&pr)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrNotFound
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &pr, nil
 }
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPullRequestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		prID      string
+	}{
+		{name: "simple", createdAt: time.Date(2025, 11, 3, 10, 15, 0, 0, time.UTC), prID: "pr-1001"},
+		{name: "nanoseconds", createdAt: time.Date(2025, 11, 3, 10, 15, 0, 123456789, time.UTC), prID: "pr-1002"},
+		{name: "non-UTC zone", createdAt: time.Date(2025, 11, 3, 13, 15, 0, 0, time.FixedZone("MSK", 3*60*60)), prID: "pr-1003"},
+		{name: "separator in id", createdAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), prID: "pr|with|pipes"},
+		{name: "empty id", createdAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), prID: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := encodePullRequestCursor(tt.createdAt, tt.prID)
+			createdAt, prID, err := decodePullRequestCursor(cursor)
+			if err != nil {
+				t.Fatalf("decodePullRequestCursor(%q) error: %v", cursor, err)
+			}
+			if !createdAt.Equal(tt.createdAt) {
+				t.Errorf("created_at = %v, want %v", createdAt, tt.createdAt)
+			}
+			if prID != tt.prID {
+				t.Errorf("pull_request_id = %q, want %q", prID, tt.prID)
+			}
+		})
+	}
+}
+
+func TestDecodePullRequestCursorMalformed(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "!!!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte("2025-11-03T10:15:00Z|pr-1"))},
+		{name: "no separator", cursor: encode("2025-11-03T10:15:00Z")},
+		{name: "bad time", cursor: encode("yesterday|pr-1")},
+		{name: "empty time", cursor: encode("|pr-1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodePullRequestCursor(tt.cursor)
+			if !errors.Is(err, entities.ErrInvalidFilter) {
+				t.Errorf("decodePullRequestCursor(%q) error = %v, want ErrInvalidFilter", tt.cursor, err)
+			}
+		})
+	}
+}
+
+// Walking the pages of List must return every matching pull request exactly
+// once and in order, also when several were created at the same moment.
+func TestListPagesThroughRows(t *testing.T) {
+	db := openTestDB(t)
+
+	mustCreate(t, db,
+		&entities.Team{TeamName: "core"},
+		&entities.User{UserID: "author", Username: "author", TeamName: "core", IsActive: true},
+	)
+
+	base := time.Date(2025, 11, 3, 10, 0, 0, 0, time.UTC)
+	seeds := []struct {
+		id        string
+		createdAt time.Time
+		status    string
+	}{
+		{id: "pr-a", createdAt: base, status: entities.PRStatusOpen},
+		{id: "pr-b", createdAt: base.Add(time.Minute), status: entities.PRStatusMerged},
+		{id: "pr-c", createdAt: base.Add(time.Minute), status: entities.PRStatusOpen},
+		{id: "pr-d", createdAt: base.Add(time.Minute), status: entities.PRStatusOpen},
+		{id: "pr-e", createdAt: base.Add(2*time.Minute + 250*time.Microsecond), status: entities.PRStatusOpen},
+		{id: "pr-f", createdAt: base.Add(3 * time.Minute), status: entities.PRStatusMerged},
+		{id: "pr-g", createdAt: base.Add(4 * time.Minute), status: entities.PRStatusOpen},
+	}
+	for _, seed := range seeds {
+		mustCreate(t, db, &entities.PullRequest{PullRequestID: seed.id, PullRequestName: seed.id,
+			AuthorID: "author", Status: seed.status, CreatedAt: seed.createdAt})
+	}
+
+	service := NewPullRequestService(db, nil)
+	tests := []struct {
+		name   string
+		filter entities.PullRequestFilter
+		want   []string
+	}{
+		{name: "newest first", filter: entities.PullRequestFilter{Limit: 3},
+			want: []string{"pr-g", "pr-f", "pr-e", "pr-d", "pr-c", "pr-b", "pr-a"}},
+		{name: "oldest first", filter: entities.PullRequestFilter{Limit: 3, Sort: "asc"},
+			want: []string{"pr-a", "pr-b", "pr-c", "pr-d", "pr-e", "pr-f", "pr-g"}},
+		{name: "open only", filter: entities.PullRequestFilter{Limit: 2, Status: entities.PRStatusOpen},
+			want: []string{"pr-g", "pr-e", "pr-d", "pr-c", "pr-a"}},
+		{name: "page size of all rows", filter: entities.PullRequestFilter{Limit: 7},
+			want: []string{"pr-g", "pr-f", "pr-e", "pr-d", "pr-c", "pr-b", "pr-a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			got := []string{}
+			for pages := 0; ; pages++ {
+				if pages > len(seeds) {
+					t.Fatalf("no last page after %d pages: %v", pages, got)
+				}
+
+				page, next, err := service.List(&filter)
+				if err != nil {
+					t.Fatalf("List() error: %v", err)
+				}
+				if len(page) > filter.Limit {
+					t.Fatalf("page of %d rows, limit %d", len(page), filter.Limit)
+				}
+				for _, pr := range page {
+					got = append(got, pr.PullRequestID)
+				}
+
+				if next == "" {
+					break
+				}
+				filter.Cursor = next
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
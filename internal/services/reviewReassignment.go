@@ -0,0 +1,486 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reassignmentBatchSize caps the rows written, and the reviews matched, by one
+// statement of a batch reassignment.
+const reassignmentBatchSize = 500
+
+type reassignmentCacheKey struct{}
+
+// cachedSelector is implemented by the built-in selectors, which take review
+// loads and rotation cursors from the reassignment cache when there is one.
+type cachedSelector interface {
+	readsReassignmentCache()
+}
+
+// reassignmentCache holds what reviewerAssigner reads to pick replacements,
+// loaded once for a whole batch reassignment. While a transaction carries it,
+// see withReassignmentCache, the assigner and the built-in selectors read it
+// instead of the database: picks count towards the loads and move the round
+// robin cursors in memory, and the batch writes everything at the end. Any
+// lookup of something that was not loaded goes to the database as usual.
+type reassignmentCache struct {
+	users     map[string]entities.User
+	memberOf  map[string]map[string]bool
+	declined  map[string][]string
+	fallbacks map[string][]string
+	teams     map[string]entities.Team
+	members   map[string][]entities.User
+	capacity  map[string]*int64
+	load      map[string]int64
+	cursors   map[string]string
+
+	// sync writes the changes picked so far, for selectors that read them
+	// from the database.
+	sync func() error
+}
+
+// poolCandidate is an eligible reviewer of a team together with their review
+// capacity and current number of open reviews.
+type poolCandidate struct {
+	UserID      string
+	Username    string
+	HomeTeam    string
+	PoolTeam    string
+	Capacity    *int64
+	OpenReviews int64
+}
+
+func withReassignmentCache(tx *gorm.DB, cache *reassignmentCache) *gorm.DB {
+	return tx.WithContext(context.WithValue(tx.Statement.Context, reassignmentCacheKey{}, cache))
+}
+
+// reassignmentCacheOf returns the cache carried by tx or nil. All lookups on a
+// nil cache miss.
+func reassignmentCacheOf(tx *gorm.DB) *reassignmentCache {
+	if tx == nil || tx.Statement == nil || tx.Statement.Context == nil {
+		return nil
+	}
+	cache, _ := tx.Statement.Context.Value(reassignmentCacheKey{}).(*reassignmentCache)
+	return cache
+}
+
+func (c *reassignmentCache) user(userID string) (entities.User, bool) {
+	if c == nil {
+		return entities.User{}, false
+	}
+	user, ok := c.users[userID]
+	return user, ok
+}
+
+// reviewsFor returns the teams userID reviews for.
+func (c *reassignmentCache) reviewsFor(userID string) (map[string]bool, bool) {
+	if c == nil {
+		return nil, false
+	}
+	teams, ok := c.memberOf[userID]
+	return teams, ok
+}
+
+func (c *reassignmentCache) declinedBy(authorID string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	declined, ok := c.declined[authorID]
+	return declined, ok
+}
+
+func (c *reassignmentCache) fallbacksOf(teamName string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	teams, ok := c.fallbacks[teamName]
+	return teams, ok
+}
+
+func (c *reassignmentCache) team(teamName string) (entities.Team, bool) {
+	if c == nil {
+		return entities.Team{}, false
+	}
+	team, ok := c.teams[teamName]
+	return team, ok
+}
+
+// eligible returns the loaded members of teamName who are not excluded and
+// are below their open review limit, like reviewerAssigner.candidates.
+func (c *reassignmentCache) eligible(teamName string, excluded []string) ([]entities.User, bool) {
+	if c == nil {
+		return nil, false
+	}
+	members, ok := c.members[teamName]
+	if !ok {
+		return nil, false
+	}
+
+	candidates := []entities.User{}
+	for _, member := range members {
+		capacity := c.capacity[member.UserID]
+		if !slices.Contains(excluded, member.UserID) && (capacity == nil || c.load[member.UserID] < *capacity) {
+			candidates = append(candidates, member)
+		}
+	}
+	return candidates, true
+}
+
+// loads returns the open review counts of userIDs if all of them are loaded.
+func (c *reassignmentCache) loads(userIDs []string) (map[string]int64, bool) {
+	if c == nil {
+		return nil, false
+	}
+	loads := make(map[string]int64, len(userIDs))
+	for _, userID := range userIDs {
+		load, ok := c.load[userID]
+		if !ok {
+			return nil, false
+		}
+		loads[userID] = load
+	}
+	return loads, true
+}
+
+// cursor returns the round robin position of teamName once its cursor is
+// locked for the batch.
+func (c *reassignmentCache) cursor(teamName string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	cursor, ok := c.cursors[teamName]
+	return cursor, ok
+}
+
+// batchReassignment replaces reviewers on many locked pull requests at once.
+// Every replacement is picked by reviewerAssigner.pickReplacement, the same as
+// for a single reviewer, on a transaction carrying a reassignmentCache, so the
+// number of queries does not grow with the number of reviews. The changes are
+// collected and written in batches.
+type batchReassignment struct {
+	tx       *gorm.DB
+	cache    *reassignmentCache
+	assigner *reviewerAssigner
+
+	removed [][]interface{}
+	added   []entities.PullRequestReviewer
+	events  []entities.ReviewerAssignmentEvent
+}
+
+// replaceReviewers replaces userIDs on the locked OPEN prs and reports what
+// changed on each of them. A review without a replacement is recorded as
+// eventType and its slot is left to the pending queue. Reviewers of one pull
+// request are replaced in user_id order.
+func replaceReviewers(tx *gorm.DB, assigner *reviewerAssigner, prs []entities.PullRequest, userIDs []string, eventType string, reason string) ([]entities.PullRequestReassignment, error) {
+	reviewed := []entities.PullRequestReassignment{}
+	if len(prs) == 0 || len(userIDs) == 0 {
+		return reviewed, nil
+	}
+
+	cache, err := loadReassignmentCache(tx, prs, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &batchReassignment{
+		tx:       withReassignmentCache(tx, cache),
+		cache:    cache,
+		assigner: assigner,
+	}
+	cache.sync = batch.flush
+
+	queued := []entities.PendingAssignment{}
+	complete := []string{}
+	for i := range prs {
+		outcome, err := batch.replaceOn(&prs[i], userIDs, eventType, reason)
+		if err != nil {
+			return nil, err
+		}
+		reviewed = append(reviewed, outcome)
+
+		if outcome.UnfilledSlots > 0 {
+			queued = append(queued, entities.PendingAssignment{PullRequestID: prs[i].PullRequestID})
+		} else {
+			complete = append(complete, prs[i].PullRequestID)
+		}
+	}
+
+	if err := batch.flush(); err != nil {
+		return nil, err
+	}
+
+	if len(queued) > 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			CreateInBatches(&queued, reassignmentBatchSize).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	for chunk := range slices.Chunk(complete, reassignmentBatchSize) {
+		if err := tx.Where("pull_request_id IN ?", chunk).Delete(&entities.PendingAssignment{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return reviewed, nil
+}
+
+// loadReassignmentCache loads everything replacements of userIDs on prs are
+// picked from: the old reviewers and their memberships, the declines of the
+// authors, the teams replacements come from with their fallback teams, and
+// the eligible members of all of those teams with their loads.
+func loadReassignmentCache(tx *gorm.DB, prs []entities.PullRequest, userIDs []string) (*reassignmentCache, error) {
+	cache := &reassignmentCache{
+		users:     make(map[string]entities.User),
+		memberOf:  make(map[string]map[string]bool),
+		declined:  make(map[string][]string),
+		fallbacks: make(map[string][]string),
+		teams:     make(map[string]entities.Team),
+		members:   make(map[string][]entities.User),
+		capacity:  make(map[string]*int64),
+		load:      make(map[string]int64),
+		cursors:   make(map[string]string),
+	}
+
+	var users []entities.User
+	if err := tx.Where("user_id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		cache.users[user.UserID] = user
+		cache.memberOf[user.UserID] = make(map[string]bool)
+	}
+
+	var memberships []entities.TeamMembership
+	err := tx.Where("user_id IN ? AND role <> ?", userIDs, entities.MembershipRoleObserver).
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		if teams, ok := cache.memberOf[membership.UserID]; ok {
+			teams[membership.TeamName] = true
+		}
+	}
+
+	authorIDs := make(map[string]bool)
+	replacementTeams := make(map[string]bool)
+	for i := range prs {
+		authorIDs[prs[i].AuthorID] = true
+		for _, reviewer := range prs[i].AssignedReviewers {
+			oldReviewer, ok := cache.users[reviewer.UserID]
+			if !ok || !slices.Contains(userIDs, reviewer.UserID) {
+				continue
+			}
+			teamName, err := replacementTeam(withReassignmentCache(tx, cache), &prs[i], &oldReviewer)
+			if err != nil {
+				return nil, err
+			}
+			replacementTeams[teamName] = true
+		}
+	}
+
+	if err := cache.loadDeclines(tx, mapKeys(authorIDs)); err != nil {
+		return nil, err
+	}
+	if err := cache.loadFallbacks(tx, mapKeys(replacementTeams)); err != nil {
+		return nil, err
+	}
+
+	teams := make(map[string]bool)
+	for teamName := range replacementTeams {
+		teams[teamName] = true
+		for _, fallback := range cache.fallbacks[teamName] {
+			teams[fallback] = true
+		}
+	}
+	if err := cache.loadTeams(tx, mapKeys(teams)); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+func (c *reassignmentCache) loadDeclines(tx *gorm.DB, authorIDs []string) error {
+	var declines []entities.ReviewDecline
+	err := tx.Where("author_id IN ? AND created_at > ?", authorIDs, time.Now().Add(-declineCooldown)).
+		Find(&declines).Error
+	if err != nil {
+		return err
+	}
+
+	for _, authorID := range authorIDs {
+		c.declined[authorID] = []string{}
+	}
+	for _, decline := range declines {
+		c.declined[decline.AuthorID] = append(c.declined[decline.AuthorID], decline.UserID)
+	}
+
+	return nil
+}
+
+func (c *reassignmentCache) loadFallbacks(tx *gorm.DB, teamNames []string) error {
+	var fallbacks []entities.TeamFallback
+	err := tx.Where("team_name IN ?", teamNames).
+		Order("team_name").
+		Order("priority").
+		Order("fallback_team_name").
+		Find(&fallbacks).Error
+	if err != nil {
+		return err
+	}
+
+	for _, teamName := range teamNames {
+		c.fallbacks[teamName] = []string{}
+	}
+	for _, fallback := range fallbacks {
+		c.fallbacks[fallback.TeamName] = append(c.fallbacks[fallback.TeamName], fallback.FallbackTeamName)
+	}
+
+	return nil
+}
+
+// loadTeams loads the settings and the eligible reviewers of teamNames. A team
+// that does not exist, such as the empty home team of a user without one, is
+// loaded as having no reviewers.
+func (c *reassignmentCache) loadTeams(tx *gorm.DB, teamNames []string) error {
+	for _, teamName := range teamNames {
+		c.members[teamName] = []entities.User{}
+	}
+
+	var teams []entities.Team
+	if err := tx.Where("team_name IN ?", teamNames).Find(&teams).Error; err != nil {
+		return err
+	}
+	for _, team := range teams {
+		c.teams[team.TeamName] = team
+	}
+
+	var candidates []poolCandidate
+	err := eligibleReviewers(tx).
+		Select("users.user_id, users.username, users.team_name AS home_team, m.team_name AS pool_team, "+
+			reviewCapacity+" AS capacity, "+openReviewCount+" AS open_reviews").
+		Joins("JOIN team_memberships m ON m.user_id = users.user_id AND m.role <> ?", entities.MembershipRoleObserver).
+		Where("m.team_name IN ?", teamNames).
+		Order("users.user_id").
+		Scan(&candidates).Error
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		c.members[candidate.PoolTeam] = append(c.members[candidate.PoolTeam], entities.User{
+			UserID:   candidate.UserID,
+			Username: candidate.Username,
+			TeamName: candidate.HomeTeam,
+			IsActive: true,
+		})
+		c.capacity[candidate.UserID] = candidate.Capacity
+		c.load[candidate.UserID] = candidate.OpenReviews
+	}
+
+	return nil
+}
+
+// replaceOn replaces every one of userIDs reviewing pr, like unassign with
+// refill does for a single reviewer, and keeps pr.AssignedReviewers up to date.
+func (b *batchReassignment) replaceOn(pr *entities.PullRequest, userIDs []string, eventType string, reason string) (entities.PullRequestReassignment, error) {
+	outcome := entities.PullRequestReassignment{
+		PullRequestID:    pr.PullRequestID,
+		RemovedReviewers: []string{},
+		AddedReviewers:   []string{},
+	}
+
+	removed := slices.DeleteFunc(reviewerUserIDs(pr.AssignedReviewers), func(userID string) bool {
+		return !slices.Contains(userIDs, userID)
+	})
+	sort.Strings(removed)
+
+	for _, oldUserID := range removed {
+		added, err := b.assigner.pickReplacement(b.tx, pr, oldUserID, "")
+		if err != nil && !errors.Is(err, entities.ErrNoReplacement) {
+			return outcome, err
+		}
+
+		b.removed = append(b.removed, []interface{}{pr.PullRequestID, oldUserID})
+		if _, ok := b.cache.load[oldUserID]; ok {
+			b.cache.load[oldUserID]--
+		}
+		pr.AssignedReviewers = slices.DeleteFunc(pr.AssignedReviewers, func(reviewer entities.PullRequestReviewer) bool {
+			return reviewer.UserID == oldUserID
+		})
+		outcome.RemovedReviewers = append(outcome.RemovedReviewers, oldUserID)
+
+		if len(added) == 0 {
+			b.events = append(b.events, entities.ReviewerAssignmentEvent{
+				PullRequestID: pr.PullRequestID,
+				EventType:     eventType,
+				UserID:        oldUserID,
+				Reason:        reason,
+			})
+			continue
+		}
+
+		b.added = append(b.added, added...)
+		b.events = append(b.events, replacementEvents(added, oldUserID, reason)...)
+		pr.AssignedReviewers = append(pr.AssignedReviewers, added...)
+		outcome.AddedReviewers = append(outcome.AddedReviewers, reviewerUserIDs(added)...)
+	}
+
+	outcome.UnfilledSlots = max(0, pr.RequiredReviewers-len(pr.AssignedReviewers))
+	return outcome, nil
+}
+
+// flush writes the changes collected so far and the round robin cursors.
+func (b *batchReassignment) flush() error {
+	for chunk := range slices.Chunk(b.removed, reassignmentBatchSize) {
+		if err := b.tx.Where("(pull_request_id, user_id) IN ?", chunk).
+			Delete(&entities.PullRequestReviewer{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(b.added) > 0 {
+		if err := b.tx.CreateInBatches(&b.added, reassignmentBatchSize).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(b.events) > 0 {
+		if err := b.tx.CreateInBatches(&b.events, reassignmentBatchSize).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, teamName := range slices.Sorted(maps.Keys(b.cache.cursors)) {
+		err := b.tx.Model(&entities.ReviewerRotationCursor{}).
+			Where("team_name = ?", teamName).
+			Updates(map[string]interface{}{
+				"last_user_id": b.cache.cursors[teamName],
+				"updated_at":   time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	b.removed, b.added, b.events = nil, nil, nil
+	return nil
+}
+
+func mapKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
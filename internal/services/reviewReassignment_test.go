@@ -0,0 +1,295 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+var legacyReviewers = []string{"l1", "l2", "l3"}
+
+// seedReassignment creates the legacy team, whose members review prCount OPEN
+// pull requests of other teams, and teams using every built-in strategy to
+// replace them from: through a membership in the author's team, through
+// fallback teams, with a review limit, a decline and an observer in the way.
+func seedReassignment(t *testing.T, db *gorm.DB, prCount int) {
+	t.Helper()
+
+	limit := 2
+	mustCreate(t, db,
+		&[]entities.Team{
+			{TeamName: "backend", ReviewerStrategy: entities.ReviewerStrategyLeastLoaded},
+			{TeamName: "platform", ReviewerStrategy: entities.ReviewerStrategyRoundRobin},
+			{TeamName: "mobile", ReviewerStrategy: entities.ReviewerStrategyRandom},
+			{TeamName: "legacy", ReviewerStrategy: entities.ReviewerStrategyLeastLoaded},
+		},
+		&[]entities.User{
+			{UserID: "b1", Username: "b1", TeamName: "backend", IsActive: true},
+			{UserID: "b2", Username: "b2", TeamName: "backend", IsActive: true},
+			{UserID: "b3", Username: "b3", TeamName: "backend", IsActive: true},
+			{UserID: "b4", Username: "b4", TeamName: "backend", IsActive: true, MaxOpenReviews: &limit},
+			{UserID: "p1", Username: "p1", TeamName: "platform", IsActive: true},
+			{UserID: "p2", Username: "p2", TeamName: "platform", IsActive: true},
+			{UserID: "p3", Username: "p3", TeamName: "platform", IsActive: true},
+			{UserID: "p4", Username: "p4", TeamName: "platform", IsActive: true},
+			{UserID: "m1", Username: "m1", TeamName: "mobile", IsActive: true},
+			{UserID: "m2", Username: "m2", TeamName: "mobile", IsActive: true},
+			{UserID: "m3", Username: "m3", TeamName: "mobile", IsActive: true},
+			{UserID: "l1", Username: "l1", TeamName: "legacy", IsActive: true},
+			{UserID: "l2", Username: "l2", TeamName: "legacy", IsActive: true},
+			{UserID: "l3", Username: "l3", TeamName: "legacy", IsActive: true},
+		},
+		&[]entities.TeamMembership{
+			{TeamName: "backend", UserID: "b1", Role: entities.MembershipRoleMember},
+			{TeamName: "backend", UserID: "b2", Role: entities.MembershipRoleMember},
+			{TeamName: "backend", UserID: "b3", Role: entities.MembershipRoleMember},
+			{TeamName: "backend", UserID: "b4", Role: entities.MembershipRoleMember},
+			{TeamName: "backend", UserID: "l1", Role: entities.MembershipRoleMember},
+			{TeamName: "backend", UserID: "m3", Role: entities.MembershipRoleObserver},
+			{TeamName: "platform", UserID: "p1", Role: entities.MembershipRoleMember},
+			{TeamName: "platform", UserID: "p2", Role: entities.MembershipRoleMember},
+			{TeamName: "platform", UserID: "p3", Role: entities.MembershipRoleMember},
+			{TeamName: "platform", UserID: "p4", Role: entities.MembershipRoleMember},
+			{TeamName: "mobile", UserID: "m1", Role: entities.MembershipRoleMember},
+			{TeamName: "mobile", UserID: "m2", Role: entities.MembershipRoleMember},
+			{TeamName: "mobile", UserID: "m3", Role: entities.MembershipRoleMember},
+			{TeamName: "legacy", UserID: "l1", Role: entities.MembershipRoleMember},
+			{TeamName: "legacy", UserID: "l2", Role: entities.MembershipRoleMember},
+			{TeamName: "legacy", UserID: "l3", Role: entities.MembershipRoleMember},
+		},
+		&[]entities.TeamFallback{
+			{TeamName: "legacy", FallbackTeamName: "platform", Priority: 1},
+			{TeamName: "legacy", FallbackTeamName: "mobile", Priority: 2},
+			{TeamName: "backend", FallbackTeamName: "mobile", Priority: 1},
+		},
+	)
+
+	authors := []string{"b1", "p1", "m1", "b2"}
+	for i := 0; i < prCount; i++ {
+		prID := fmt.Sprintf("pr-%04d", i)
+		reviewers := []entities.PullRequestReviewer{
+			{PullRequestID: prID, UserID: legacyReviewers[i%3]},
+			{PullRequestID: prID, UserID: legacyReviewers[(i+1)%3]},
+		}
+		if i%3 == 0 {
+			reviewers = append(reviewers, entities.PullRequestReviewer{PullRequestID: prID, UserID: "p2"})
+		}
+		mustCreate(t, db,
+			&entities.PullRequest{PullRequestID: prID, PullRequestName: prID, AuthorID: authors[i%len(authors)],
+				Status: entities.PRStatusOpen, RequiredReviewers: 2 + i%2},
+			&reviewers,
+		)
+	}
+
+	mustCreate(t, db, &entities.ReviewDecline{PullRequestID: "pr-0000", UserID: "b3", AuthorID: "b1",
+		ReasonCode: entities.DeclineReasonNoContext})
+}
+
+// reassignmentState is what a reassignment leaves behind, comparable between runs.
+type reassignmentState struct {
+	reviewers map[string][]string
+	events    []string
+	cursors   map[string]string
+	pending   []string
+}
+
+func captureReassignmentState(t *testing.T, tx *gorm.DB) reassignmentState {
+	t.Helper()
+
+	var reviewers []entities.PullRequestReviewer
+	if err := tx.Order("pull_request_id, user_id").Find(&reviewers).Error; err != nil {
+		t.Fatal(err)
+	}
+	var events []entities.ReviewerAssignmentEvent
+	if err := tx.Order("pull_request_id, id").Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	var cursors []entities.ReviewerRotationCursor
+	if err := tx.Find(&cursors).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	state := reassignmentState{
+		reviewers: make(map[string][]string),
+		events:    []string{},
+		cursors:   make(map[string]string),
+		pending:   []string{},
+	}
+	for _, reviewer := range reviewers {
+		state.reviewers[reviewer.PullRequestID] = append(state.reviewers[reviewer.PullRequestID],
+			reviewer.UserID+"/"+reviewer.Source)
+	}
+	for _, event := range events {
+		previous := ""
+		if event.PreviousUserID != nil {
+			previous = *event.PreviousUserID
+		}
+		state.events = append(state.events, fmt.Sprintf("%s %s %s<-%s %s",
+			event.PullRequestID, event.EventType, event.UserID, previous, event.Reason))
+	}
+	for _, cursor := range cursors {
+		state.cursors[cursor.TeamName] = cursor.LastUserID
+	}
+	if err := tx.Model(&entities.PendingAssignment{}).Order("pull_request_id").
+		Pluck("pull_request_id", &state.pending).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return state
+}
+
+// runLegacyReassignment deactivates the legacy team and replaces its members
+// with replace in a transaction that is rolled back, and returns the state it
+// would leave behind.
+func runLegacyReassignment(t *testing.T, db *gorm.DB, replace func(tx *gorm.DB, prs []entities.PullRequest) error) reassignmentState {
+	t.Helper()
+
+	var state reassignmentState
+	err := runTransaction(db, true, func(tx *gorm.DB) error {
+		if err := tx.Model(&entities.User{}).Where("user_id IN ?", legacyReviewers).Update("is_active", false).Error; err != nil {
+			return err
+		}
+
+		var prs []entities.PullRequest
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("AssignedReviewers").
+			Preload("Author").
+			Where("status = ? AND pull_request_id IN (SELECT pull_request_id FROM pull_request_reviewers WHERE user_id IN ?)",
+				entities.PRStatusOpen, legacyReviewers).
+			Order("pull_request_id").
+			Find(&prs).Error
+		if err != nil {
+			return err
+		}
+
+		if err := replace(tx, prs); err != nil {
+			return err
+		}
+		state = captureReassignmentState(t, tx)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reassignment: %v", err)
+	}
+
+	return state
+}
+
+func TestBatchReassignmentMatchesSingleReplacements(t *testing.T) {
+	db := openTestDB(t)
+	seedReassignment(t, db, 40)
+	assigner := newReviewerAssigner(nil)
+
+	batch := runLegacyReassignment(t, db, func(tx *gorm.DB, prs []entities.PullRequest) error {
+		_, err := replaceReviewers(tx, assigner, prs, legacyReviewers,
+			entities.AssignmentEventUnassignedDeactivation, reasonTeamDeactivated)
+		return err
+	})
+
+	single := runLegacyReassignment(t, db, func(tx *gorm.DB, prs []entities.PullRequest) error {
+		for i := range prs {
+			pr := &prs[i]
+			removed := slices.DeleteFunc(reviewerUserIDs(pr.AssignedReviewers), func(userID string) bool {
+				return !slices.Contains(legacyReviewers, userID)
+			})
+			sort.Strings(removed)
+
+			for _, userID := range removed {
+				if err := tx.Where("pull_request_id = ?", pr.PullRequestID).Find(&pr.AssignedReviewers).Error; err != nil {
+					return err
+				}
+				_, err := assigner.unassign(tx, pr, userID, true,
+					entities.AssignmentEventUnassignedDeactivation, reasonTeamDeactivated)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	for prID, want := range single.reviewers {
+		if got := batch.reviewers[prID]; !slices.Equal(got, want) {
+			t.Errorf("%s reviewers: batch %v, one by one %v", prID, got, want)
+		}
+	}
+	if len(batch.reviewers) != len(single.reviewers) {
+		t.Errorf("pull requests with reviewers: batch %d, one by one %d", len(batch.reviewers), len(single.reviewers))
+	}
+	if !slices.Equal(batch.events, single.events) {
+		t.Errorf("events differ:\nbatch      %v\none by one %v", batch.events, single.events)
+	}
+	if fmt.Sprint(batch.cursors) != fmt.Sprint(single.cursors) {
+		t.Errorf("cursors: batch %v, one by one %v", batch.cursors, single.cursors)
+	}
+	if !slices.Equal(batch.pending, single.pending) {
+		t.Errorf("pending: batch %v, one by one %v", batch.pending, single.pending)
+	}
+}
+
+// queryCounter is a gorm logger that counts the statements it is told about.
+type queryCounter struct {
+	logger.Interface
+	queries int
+}
+
+func (c *queryCounter) LogMode(logger.LogLevel) logger.Interface {
+	return c
+}
+
+func (c *queryCounter) Trace(context.Context, time.Time, func() (string, int64), error) {
+	c.queries++
+}
+
+func TestBatchReassignmentQueriesDoNotGrowWithReviews(t *testing.T) {
+	queries := func(prCount int) int {
+		db := openTestDB(t)
+		seedReassignment(t, db, prCount)
+
+		counter := &queryCounter{Interface: logger.Discard}
+		var counted int
+		runLegacyReassignment(t, db.Session(&gorm.Session{Logger: counter}), func(tx *gorm.DB, prs []entities.PullRequest) error {
+			before := counter.queries
+			_, err := replaceReviewers(tx, newReviewerAssigner(nil), prs, legacyReviewers,
+				entities.AssignmentEventUnassignedDeactivation, reasonTeamDeactivated)
+			counted = counter.queries - before
+			return err
+		})
+		return counted
+	}
+
+	small, large := queries(12), queries(120)
+	if small != large {
+		t.Errorf("queries for 12 pull requests = %d, for 120 = %d", small, large)
+	}
+}
+
+func TestMassDeactivateTeamUsersWithinBudget(t *testing.T) {
+	db := openTestDB(t)
+	seedReassignment(t, db, 300)
+	service := NewTeamService(db, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	start := time.Now()
+	report, err := service.MassDeactivateTeamUsers("legacy", false)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("MassDeactivateTeamUsers() error: %v", err)
+	}
+
+	if len(report.ReviewedPRs) != 300 {
+		t.Errorf("reviewed pull requests = %d, want 300", len(report.ReviewedPRs))
+	}
+	if elapsed > 100*time.Millisecond {
+		t.Errorf("deactivating a team with 300 open pull requests took %v, want under 100ms", elapsed)
+	}
+}
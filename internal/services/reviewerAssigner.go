@@ -0,0 +1,720 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reasons recorded in the reviewer assignment history.
+const (
+	reasonCreated         = "pull request created"
+	reasonMarkedReady     = "pull request marked ready"
+	reasonReopened        = "pull request reopened"
+	reasonNotEligible     = "reviewer no longer eligible on reopen"
+	reasonPendingFilled   = "missing reviewer slot filled"
+	reasonReassigned      = "reviewer reassigned"
+	reasonReassignRefill  = "missing reviewer slot filled on reassignment"
+	reasonTeamDeactivated = "team deactivated"
+	reasonReviewSLA       = "review SLA exceeded"
+	reasonDeclined        = "reviewer declined"
+	reasonAddedManually   = "reviewer added manually"
+	reasonRemovedManually = "reviewer removed manually"
+	reasonUserDeactivated = "reviewer deactivated"
+	reasonLeftTeam        = "reviewer left the team"
+	reasonAuthorMoved     = "author moved to another team"
+)
+
+// declineCooldown is how long a reviewer who declined a pull request is not
+// picked again for pull requests of the same author.
+const declineCooldown = 7 * 24 * time.Hour
+
+// reviewerRequest describes the reviewers a newly opened pull request needs.
+type reviewerRequest struct {
+	pullRequestID  string
+	author         entities.User
+	authorTeam     entities.Team
+	changedFiles   []string
+	requiredSkills []string
+	// requested reviewers are validated by the caller and assigned before anyone else.
+	requested []entities.User
+	excluded  []string
+}
+
+type reviewerAssigner struct {
+	selectors map[string]interfaces.ReviewerSelector
+}
+
+func newReviewerAssigner(selectors map[string]interfaces.ReviewerSelector) *reviewerAssigner {
+	if selectors == nil {
+		selectors = DefaultReviewerSelectors()
+	}
+	return &reviewerAssigner{
+		selectors: selectors,
+	}
+}
+
+func (ra *reviewerAssigner) selectorFor(strategy string) interfaces.ReviewerSelector {
+	if selector, ok := ra.selectors[strategy]; ok {
+		return selector
+	}
+	if selector, ok := ra.selectors[entities.DefaultReviewerStrategy]; ok {
+		return selector
+	}
+	return NewLeastLoadedSelector()
+}
+
+func (ra *reviewerAssigner) hasStrategy(strategy string) bool {
+	_, ok := ra.selectors[strategy]
+	return ok
+}
+
+// pick selects up to count active members of teamName, skipping excluded users,
+// with the strategy configured for that team.
+func (ra *reviewerAssigner) pick(tx *gorm.DB, teamName string, count int, excluded []string) ([]entities.User, error) {
+	if count <= 0 {
+		return []entities.User{}, nil
+	}
+
+	candidates, err := ra.candidates(tx, teamName, excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	return ra.selectFrom(tx, teamName, candidates, count)
+}
+
+// pickWithSkill selects one eligible member of teamName who has the given skill.
+func (ra *reviewerAssigner) pickWithSkill(tx *gorm.DB, teamName string, skill string, excluded []string) ([]entities.User, error) {
+	candidates, err := ra.candidates(tx, teamName, excluded)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return []entities.User{}, nil
+	}
+
+	var skilled []string
+	err = tx.Model(&entities.UserSkill{}).
+		Where("skill = ? AND user_id IN ?", skill, appendUserIDs(nil, candidates)).
+		Pluck("user_id", &skilled).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hasSkill := make(map[string]bool, len(skilled))
+	for _, userID := range skilled {
+		hasSkill[userID] = true
+	}
+
+	matching := []entities.User{}
+	for _, candidate := range candidates {
+		if hasSkill[candidate.UserID] {
+			matching = append(matching, candidate)
+		}
+	}
+
+	return ra.selectFrom(tx, teamName, matching, 1)
+}
+
+func (ra *reviewerAssigner) selectFrom(tx *gorm.DB, teamName string, candidates []entities.User, count int) ([]entities.User, error) {
+	if len(candidates) == 0 {
+		return []entities.User{}, nil
+	}
+
+	cache := reassignmentCacheOf(tx)
+	team, ok := cache.team(teamName)
+	if !ok {
+		if err := tx.Where("team_name = ?", teamName).First(&team).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	selector := ra.selectorFor(team.ReviewerStrategy)
+	if cache == nil {
+		return selector.Select(tx, teamName, candidates, count)
+	}
+
+	// Selectors that do not read the cache read the database, which has to
+	// catch up with the picks made so far.
+	if _, ok := selector.(cachedSelector); !ok {
+		if err := cache.sync(); err != nil {
+			return nil, err
+		}
+	}
+
+	selected, err := selector.Select(tx, teamName, candidates, count)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range selected {
+		cache.load[user.UserID]++
+	}
+
+	return selected, nil
+}
+
+// pickWithFallback picks from teamName and, only if nobody there is eligible,
+// from the fallback teams configured for teamName in priority order.
+func (ra *reviewerAssigner) pickWithFallback(tx *gorm.DB, teamName string, count int, excluded []string) ([]entities.User, []entities.User, error) {
+	local, err := ra.pick(tx, teamName, count, excluded)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(local) > 0 || count <= 0 {
+		return local, []entities.User{}, nil
+	}
+
+	fromFallback, err := ra.pickFromFallbacks(tx, teamName, count, excluded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return local, fromFallback, nil
+}
+
+func (ra *reviewerAssigner) pickFromFallbacks(tx *gorm.DB, teamName string, count int, excluded []string) ([]entities.User, error) {
+	fallbacks, err := fallbackTeams(tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []entities.User{}
+	for _, fallbackTeam := range fallbacks {
+		if len(selected) >= count {
+			break
+		}
+
+		picked, err := ra.pick(tx, fallbackTeam, count-len(selected), excluded)
+		if err != nil {
+			return nil, err
+		}
+
+		selected = append(selected, picked...)
+		excluded = appendUserIDs(excluded, picked)
+	}
+
+	return selected, nil
+}
+
+// selectInitial assigns the requested reviewers first, then picks one reviewer
+// from every team owning the changed files that is not represented yet,
+// then one reviewer for every required skill nobody picked so far has,
+// and fills the remaining slots up to max_reviewers from the author's team.
+// Ownership and skill picks stop once max_reviewers is reached; skills left
+// over then count as uncovered. Fallback teams are used only when no one from
+// the author's team was picked. It also returns the required skills no
+// eligible reviewer could cover.
+func (ra *reviewerAssigner) selectInitial(tx *gorm.DB, request reviewerRequest) ([]entities.PullRequestReviewer, []string, error) {
+	tx = withSelectionSeed(tx, request.pullRequestID)
+	declined, err := declinedReviewers(tx, request.author.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	excluded := append([]string{request.author.UserID}, request.excluded...)
+	excluded = append(excluded, declined...)
+	reviewers := toPullRequestReviewers(request.pullRequestID, request.requested, entities.ReviewerSourceRequested)
+	excluded = appendUserIDs(excluded, request.requested)
+
+	represented := make(map[string]bool)
+	for _, user := range request.requested {
+		represented[user.TeamName] = true
+	}
+	hasLocal := represented[request.authorTeam.TeamName]
+
+	owners, err := owningTeams(tx, request.changedFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limit := request.authorTeam.MaxReviewers
+	for _, teamName := range owners {
+		if len(reviewers) >= limit {
+			break
+		}
+		if represented[teamName] {
+			continue
+		}
+
+		picked, err := ra.pick(tx, teamName, 1, excluded)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if teamName == request.authorTeam.TeamName && len(picked) > 0 {
+			hasLocal = true
+		}
+		reviewers = append(reviewers, toPullRequestReviewers(request.pullRequestID, picked, entities.ReviewerSourceOwnership)...)
+		excluded = appendUserIDs(excluded, picked)
+	}
+
+	fallbacks, err := fallbackTeams(tx, request.authorTeam.TeamName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	covered, err := skillsOf(tx, reviewerUserIDs(reviewers))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uncovered := []string{}
+	for _, skill := range normalizeSkills(request.requiredSkills) {
+		if covered[skill] {
+			continue
+		}
+		if len(reviewers) >= limit {
+			uncovered = append(uncovered, skill)
+			continue
+		}
+
+		var picked []entities.User
+		for _, teamName := range append([]string{request.authorTeam.TeamName}, fallbacks...) {
+			picked, err = ra.pickWithSkill(tx, teamName, skill, excluded)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(picked) > 0 {
+				hasLocal = hasLocal || teamName == request.authorTeam.TeamName
+				break
+			}
+		}
+
+		if len(picked) == 0 {
+			uncovered = append(uncovered, skill)
+			continue
+		}
+
+		pickedSkills, err := skillsOf(tx, appendUserIDs(nil, picked))
+		if err != nil {
+			return nil, nil, err
+		}
+		for pickedSkill := range pickedSkills {
+			covered[pickedSkill] = true
+		}
+
+		reviewers = append(reviewers, toPullRequestReviewers(request.pullRequestID, picked, entities.ReviewerSourceSkill)...)
+		excluded = appendUserIDs(excluded, picked)
+	}
+
+	slots := limit - len(reviewers)
+	picked, err := ra.pick(tx, request.authorTeam.TeamName, slots, excluded)
+	if err != nil {
+		return nil, nil, err
+	}
+	reviewers = append(reviewers, toPullRequestReviewers(request.pullRequestID, picked, entities.ReviewerSourceTeam)...)
+	excluded = appendUserIDs(excluded, picked)
+
+	if !hasLocal && len(picked) == 0 && slots > 0 {
+		fromFallback, err := ra.pickFromFallbacks(tx, request.authorTeam.TeamName, slots, excluded)
+		if err != nil {
+			return nil, nil, err
+		}
+		reviewers = append(reviewers, toPullRequestReviewers(request.pullRequestID, fromFallback, entities.ReviewerSourceFallback)...)
+	}
+
+	return reviewers, uncovered, nil
+}
+
+// fill assigns up to count more reviewers to pr from the author's team or, if
+// nobody there is eligible, its fallback teams, and returns how many were added.
+// Reviewers excluded by the author or recently declined are never picked.
+func (ra *reviewerAssigner) fill(tx *gorm.DB, pr *entities.PullRequest, count int, excluded []string, reason string) (int, error) {
+	if count <= 0 {
+		return 0, nil
+	}
+
+	tx = withSelectionSeed(tx, pr.PullRequestID)
+	declined, err := declinedReviewers(tx, pr.AuthorID)
+	if err != nil {
+		return 0, err
+	}
+
+	excluded = append(excluded, pr.ExcludedReviewers...)
+	local, fromFallback, err := ra.pickWithFallback(tx, pr.Author.TeamName, count, append(excluded, declined...))
+	if err != nil {
+		return 0, err
+	}
+
+	reviewers := append(
+		toPullRequestReviewers(pr.PullRequestID, local, entities.ReviewerSourceTeam),
+		toPullRequestReviewers(pr.PullRequestID, fromFallback, entities.ReviewerSourceFallback)...,
+	)
+	if len(reviewers) == 0 {
+		return 0, nil
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reviewers)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return int(result.RowsAffected), recordEvents(tx, reviewerEvents(reviewers, entities.AssignmentEventAssigned, reason))
+}
+
+// reviewCapacity is the user's own limit of open reviews, or the team default
+// when the user has none. NULL means unlimited.
+const reviewCapacity = "COALESCE(users.max_open_reviews, " +
+	"(SELECT NULLIF(t.max_open_reviews, 0) FROM teams t WHERE t.team_name = users.team_name))"
+
+// openReviewCount is the number of OPEN pull requests the user reviews.
+const openReviewCount = "(SELECT COUNT(*) FROM pull_request_reviewers prr " +
+	"JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id " +
+	"WHERE prr.user_id = users.user_id AND pr.status = '" + entities.PRStatusOpen + "')"
+
+const hasReviewCapacity = "(" + reviewCapacity + " IS NULL OR " + reviewCapacity + " > " + openReviewCount + ")"
+
+// replace removes oldUserID from the reviewers of pr and assigns a replacement:
+// target when it is given, otherwise someone from the team the old reviewer
+// reviewed for, see replacementTeam, or its fallback teams. Slots missing up
+// to required_reviewers are refilled as well. Reviewers excluded by the author
+// are only skipped when picked automatically. pr must be locked by the caller.
+func (ra *reviewerAssigner) replace(tx *gorm.DB, pr *entities.PullRequest, oldUserID string, target string, reason string) ([]entities.PullRequestReviewer, error) {
+	added, err := ra.pickReplacement(tx, pr, oldUserID, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("pull_request_id = ? AND user_id = ?", pr.PullRequestID, oldUserID).
+		Delete(&entities.PullRequestReviewer{}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Create(&added).Error; err != nil {
+		return nil, err
+	}
+
+	if err := recordEvents(tx, replacementEvents(added, oldUserID, reason)); err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// pickReplacement picks the reviewers replace assigns in place of oldUserID
+// without changing anything. It returns entities.ErrNoReplacement when there
+// is nobody to pick.
+func (ra *reviewerAssigner) pickReplacement(tx *gorm.DB, pr *entities.PullRequest, oldUserID string, target string) ([]entities.PullRequestReviewer, error) {
+	assigned := reviewerUserIDs(pr.AssignedReviewers)
+	if !slices.Contains(assigned, oldUserID) {
+		return nil, entities.ErrUserIsNotAssignedToPR
+	}
+
+	tx = withSelectionSeed(tx, pr.PullRequestID+"/"+oldUserID)
+	oldReviewer, ok := reassignmentCacheOf(tx).user(oldUserID)
+	if !ok {
+		result := tx.Where("user_id = ?", oldUserID).First(&oldReviewer)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, entities.ErrNotFound
+		} else if result.Error != nil {
+			return nil, result.Error
+		}
+	}
+
+	declined, err := declinedReviewers(tx, pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := append([]string{pr.AuthorID}, assigned...)
+	excluded = append(excluded, pr.ExcludedReviewers...)
+	excluded = append(excluded, declined...)
+	slots := max(1, pr.RequiredReviewers-(len(assigned)-1))
+	added := []entities.PullRequestReviewer{}
+
+	if target != "" {
+		if slices.Contains(assigned, target) {
+			return nil, fmt.Errorf("%w: %s", entities.ErrReviewerAlreadyAssigned, target)
+		}
+
+		user, err := eligibleReviewer(tx, target, pr.AuthorID)
+		if err != nil {
+			return nil, err
+		}
+
+		added = append(added, toPullRequestReviewers(pr.PullRequestID, []entities.User{*user}, entities.ReviewerSourceRequested)...)
+		excluded = append(excluded, target)
+		slots--
+	}
+
+	teamName, err := replacementTeam(tx, pr, &oldReviewer)
+	if err != nil {
+		return nil, err
+	}
+
+	local, fromFallback, err := ra.pickWithFallback(tx, teamName, slots, excluded)
+	if err != nil {
+		return nil, err
+	}
+	added = append(added, toPullRequestReviewers(pr.PullRequestID, local, entities.ReviewerSourceTeam)...)
+	added = append(added, toPullRequestReviewers(pr.PullRequestID, fromFallback, entities.ReviewerSourceFallback)...)
+
+	if len(added) == 0 {
+		return nil, entities.ErrNoReplacement
+	}
+
+	return added, nil
+}
+
+// replacementEvents records added[0] as the replacement of oldUserID and the
+// other added reviewers as refilled slots.
+func replacementEvents(added []entities.PullRequestReviewer, oldUserID string, reason string) []entities.ReviewerAssignmentEvent {
+	events := reviewerEvents(added[:1], entities.AssignmentEventReassigned, reason)
+	events[0].PreviousUserID = &oldUserID
+	return append(events, reviewerEvents(added[1:], entities.AssignmentEventAssigned, reasonReassignRefill)...)
+}
+
+// replacementTeam is the author's team when oldReviewer reviews for it and the
+// old reviewer's home team otherwise, e.g. for reviewers from a fallback team.
+func replacementTeam(tx *gorm.DB, pr *entities.PullRequest, oldReviewer *entities.User) (string, error) {
+	if pr.Author.TeamName == "" || pr.Author.TeamName == oldReviewer.TeamName {
+		return oldReviewer.TeamName, nil
+	}
+
+	if teams, ok := reassignmentCacheOf(tx).reviewsFor(oldReviewer.UserID); ok {
+		if teams[pr.Author.TeamName] {
+			return pr.Author.TeamName, nil
+		}
+		return oldReviewer.TeamName, nil
+	}
+
+	var memberships int64
+	err := tx.Model(&entities.User{}).
+		Where("user_id = ?", oldReviewer.UserID).
+		Where(reviewsForTeam, pr.Author.TeamName, entities.MembershipRoleObserver).
+		Count(&memberships).Error
+	if err != nil {
+		return "", err
+	}
+	if memberships > 0 {
+		return pr.Author.TeamName, nil
+	}
+
+	return oldReviewer.TeamName, nil
+}
+
+// declinedReviewers returns the users who declined a pull request of authorID
+// within declineCooldown.
+func declinedReviewers(tx *gorm.DB, authorID string) ([]string, error) {
+	if declined, ok := reassignmentCacheOf(tx).declinedBy(authorID); ok {
+		return declined, nil
+	}
+
+	declined := []string{}
+	err := tx.Model(&entities.ReviewDecline{}).
+		Where("author_id = ? AND created_at > ?", authorID, time.Now().Add(-declineCooldown)).
+		Distinct().
+		Pluck("user_id", &declined).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return declined, nil
+}
+
+// unassign removes userID from the reviewers of the locked pr and, with refill
+// set, replaces them. Without a replacement the removal is recorded as eventType
+// and the missing slot is left to the pending queue. It returns the
+// replacement or "" when there is none.
+func (ra *reviewerAssigner) unassign(tx *gorm.DB, pr *entities.PullRequest, userID string, refill bool, eventType string, reason string) (string, error) {
+	if refill {
+		added, err := ra.replace(tx, pr, userID, "", reason)
+		if err == nil {
+			return added[0].UserID, syncPendingAssignment(tx, pr.PullRequestID)
+		}
+		if !errors.Is(err, entities.ErrNoReplacement) {
+			return "", err
+		}
+	}
+
+	if err := tx.Where("pull_request_id = ? AND user_id = ?", pr.PullRequestID, userID).
+		Delete(&entities.PullRequestReviewer{}).Error; err != nil {
+		return "", err
+	}
+
+	err := recordEvents(tx, []entities.ReviewerAssignmentEvent{{
+		PullRequestID: pr.PullRequestID,
+		EventType:     eventType,
+		UserID:        userID,
+		Reason:        reason,
+	}})
+	if err != nil {
+		return "", err
+	}
+
+	return "", syncPendingAssignment(tx, pr.PullRequestID)
+}
+
+// explicitReviewer loads a reviewer chosen by hand and checks that they exist,
+// are active and are not the author.
+func explicitReviewer(tx *gorm.DB, userID string, authorID string) (*entities.User, error) {
+	if userID == authorID {
+		return nil, fmt.Errorf("%w: %s", entities.ErrReviewerIsAuthor, userID)
+	}
+
+	var user entities.User
+	result := tx.Where("user_id = ?", userID).First(&user)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", entities.ErrReviewerNotFound, userID)
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if !user.IsActive {
+		return nil, fmt.Errorf("%w: %s", entities.ErrReviewerInactive, userID)
+	}
+
+	return &user, nil
+}
+
+// eligibleReviewer is explicitReviewer that also requires the reviewer to be
+// available and below their open review limit, the same as automatic picks.
+func eligibleReviewer(tx *gorm.DB, userID string, authorID string) (*entities.User, error) {
+	user, err := explicitReviewer(tx, userID, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var eligible int64
+	if err := eligibleReviewers(tx).Where("user_id = ?", userID).Count(&eligible).Error; err != nil {
+		return nil, err
+	}
+	if eligible == 0 {
+		return nil, fmt.Errorf("%w: %s", entities.ErrReviewerNotEligible, userID)
+	}
+
+	return user, nil
+}
+
+func (ra *reviewerAssigner) candidates(tx *gorm.DB, teamName string, excluded []string) ([]entities.User, error) {
+	if candidates, ok := reassignmentCacheOf(tx).eligible(teamName, excluded); ok {
+		return candidates, nil
+	}
+
+	query := eligibleReviewers(tx).Where(reviewsForTeam, teamName, entities.MembershipRoleObserver)
+	if len(excluded) > 0 {
+		query = query.Where("user_id NOT IN ?", excluded)
+	}
+
+	var users []entities.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// reviewsForTeam matches users with a membership in a team that lets them review its pull requests.
+const reviewsForTeam = "user_id IN (SELECT user_id FROM team_memberships WHERE team_name = ? AND role <> ?)"
+
+// eligibleReviewers scopes a users query to those who are active, available
+// right now and below their open review limit.
+func eligibleReviewers(tx *gorm.DB) *gorm.DB {
+	now := time.Now()
+	return tx.Model(&entities.User{}).
+		Where("is_active = ?", true).
+		Where("NOT EXISTS (SELECT 1 FROM user_unavailability ua "+
+			"WHERE ua.user_id = users.user_id AND ua.starts_at <= ? AND ua.ends_at > ?)", now, now).
+		Where(hasReviewCapacity)
+}
+
+func appendUserIDs(userIDs []string, users []entities.User) []string {
+	for _, user := range users {
+		userIDs = append(userIDs, user.UserID)
+	}
+	return userIDs
+}
+
+func reviewerUserIDs(reviewers []entities.PullRequestReviewer) []string {
+	userIDs := make([]string, len(reviewers))
+	for i, reviewer := range reviewers {
+		userIDs[i] = reviewer.UserID
+	}
+	return userIDs
+}
+
+// skillsOf returns the set of skills at least one of the users has.
+func skillsOf(tx *gorm.DB, userIDs []string) (map[string]bool, error) {
+	skills := make(map[string]bool)
+	if len(userIDs) == 0 {
+		return skills, nil
+	}
+
+	var found []string
+	err := tx.Model(&entities.UserSkill{}).
+		Where("user_id IN ?", userIDs).
+		Distinct().
+		Pluck("skill", &found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, skill := range found {
+		skills[skill] = true
+	}
+
+	return skills, nil
+}
+
+// fallbackTeams returns the fallback teams configured for teamName in priority order.
+func fallbackTeams(tx *gorm.DB, teamName string) ([]string, error) {
+	if teams, ok := reassignmentCacheOf(tx).fallbacksOf(teamName); ok {
+		return teams, nil
+	}
+
+	teams := []string{}
+	err := tx.Model(&entities.TeamFallback{}).
+		Where("team_name = ?", teamName).
+		Order("priority").
+		Order("fallback_team_name").
+		Pluck("fallback_team_name", &teams).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return teams, nil
+}
+
+func toPullRequestReviewers(pullRequestID string, users []entities.User, source string) []entities.PullRequestReviewer {
+	reviewers := make([]entities.PullRequestReviewer, len(users))
+	for i, user := range users {
+		reviewers[i] = entities.PullRequestReviewer{
+			PullRequestID: pullRequestID,
+			UserID:        user.UserID,
+			Source:        source,
+			AssignedAt:    time.Now(),
+		}
+	}
+
+	return reviewers
+}
+
+// reviewerEvents builds one history event of eventType for every reviewer.
+func reviewerEvents(reviewers []entities.PullRequestReviewer, eventType string, reason string) []entities.ReviewerAssignmentEvent {
+	events := make([]entities.ReviewerAssignmentEvent, len(reviewers))
+	for i, reviewer := range reviewers {
+		events[i] = entities.ReviewerAssignmentEvent{
+			PullRequestID: reviewer.PullRequestID,
+			EventType:     eventType,
+			UserID:        reviewer.UserID,
+			Reason:        reason,
+		}
+	}
+
+	return events
+}
+
+// recordEvents appends events to the reviewer assignment history.
+func recordEvents(tx *gorm.DB, events []entities.ReviewerAssignmentEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func DefaultReviewerSelectors() map[string]interfaces.ReviewerSelector {
+	return map[string]interfaces.ReviewerSelector{
+		entities.ReviewerStrategyRandom:      NewRandomSelector(),
+		entities.ReviewerStrategyLeastLoaded: NewLeastLoadedSelector(),
+		entities.ReviewerStrategyRoundRobin:  NewRoundRobinSelector(),
+	}
+}
+
+// RandomSelector picks candidates in random order. Under a selection seed,
+// see withSelectionSeed, the order is fixed by the seed.
+type RandomSelector struct{}
+
+func NewRandomSelector() *RandomSelector {
+	return &RandomSelector{}
+}
+
+func (s *RandomSelector) readsReassignmentCache() {}
+
+func (s *RandomSelector) Select(tx *gorm.DB, teamName string, candidates []entities.User, count int) ([]entities.User, error) {
+	shuffled := shuffleCandidates(tx, teamName, candidates)
+	return shuffled[:min(count, len(shuffled))], nil
+}
+
+// LeastLoadedSelector prefers candidates with the fewest OPEN pull requests to review.
+// Candidates with equal load are ordered the same way RandomSelector orders them.
+type LeastLoadedSelector struct{}
+
+func NewLeastLoadedSelector() *LeastLoadedSelector {
+	return &LeastLoadedSelector{}
+}
+
+func (s *LeastLoadedSelector) readsReassignmentCache() {}
+
+func (s *LeastLoadedSelector) Select(tx *gorm.DB, teamName string, candidates []entities.User, count int) ([]entities.User, error) {
+	if len(candidates) == 0 {
+		return []entities.User{}, nil
+	}
+
+	userIDs := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		userIDs[i] = candidate.UserID
+	}
+
+	openReviews, ok := reassignmentCacheOf(tx).loads(userIDs)
+	if !ok {
+		var err error
+		if openReviews, err = openReviewCounts(tx, userIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	ranked := shuffleCandidates(tx, teamName, candidates)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return openReviews[ranked[i].UserID] < openReviews[ranked[j].UserID]
+	})
+
+	return ranked[:min(count, len(ranked))], nil
+}
+
+// RoundRobinSelector walks team members in user_id order, continuing after
+// the last reviewer it handed out for the team. The cursor is stored in
+// reviewer_rotation_cursors and locked for the rest of the transaction, so
+// concurrent selections for one team are serialized. During a batch
+// reassignment the cursor is locked once and written back by the batch.
+type RoundRobinSelector struct{}
+
+func NewRoundRobinSelector() *RoundRobinSelector {
+	return &RoundRobinSelector{}
+}
+
+func (s *RoundRobinSelector) readsReassignmentCache() {}
+
+func (s *RoundRobinSelector) Select(tx *gorm.DB, teamName string, candidates []entities.User, count int) ([]entities.User, error) {
+	if len(candidates) == 0 || count <= 0 {
+		return []entities.User{}, nil
+	}
+
+	cache := reassignmentCacheOf(tx)
+	if last, ok := cache.cursor(teamName); ok {
+		selected := rotateAfter(candidates, last, count)
+		cache.cursors[teamName] = selected[len(selected)-1].UserID
+		return selected, nil
+	}
+
+	cursor := entities.ReviewerRotationCursor{TeamName: teamName}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&cursor).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("team_name = ?", teamName).
+		First(&cursor).Error; err != nil {
+		return nil, err
+	}
+
+	selected := rotateAfter(candidates, cursor.LastUserID, count)
+	if cache != nil {
+		cache.cursors[teamName] = selected[len(selected)-1].UserID
+		return selected, nil
+	}
+
+	err := tx.Model(&entities.ReviewerRotationCursor{}).
+		Where("team_name = ?", teamName).
+		Updates(map[string]interface{}{
+			"last_user_id": selected[len(selected)-1].UserID,
+			"updated_at":   time.Now(),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return selected, nil
+}
+
+// rotateAfter returns up to count candidates in user_id order starting right
+// after cursor and wrapping around.
+func rotateAfter(candidates []entities.User, cursor string, count int) []entities.User {
+	ordered := make([]entities.User, len(candidates))
+	copy(ordered, candidates)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].UserID < ordered[j].UserID
+	})
+
+	start := sort.Search(len(ordered), func(i int) bool {
+		return ordered[i].UserID > cursor
+	})
+
+	count = min(count, len(ordered))
+	selected := make([]entities.User, count)
+	for i := 0; i < count; i++ {
+		selected[i] = ordered[(start+i)%len(ordered)]
+	}
+
+	return selected
+}
+
+type selectionSeedKey struct{}
+
+// withSelectionSeed returns tx carrying seed for the selectors. Under a seed the
+// built-in selectors break ties the same way whenever they are given the same
+// team and candidates, so a dry run picks the reviewers the real run would.
+func withSelectionSeed(tx *gorm.DB, seed string) *gorm.DB {
+	return tx.WithContext(context.WithValue(tx.Statement.Context, selectionSeedKey{}, seed))
+}
+
+// shuffleCandidates returns a shuffled copy of candidates. Without a selection
+// seed on tx the order is random, otherwise it depends only on the seed,
+// teamName and which users are candidates.
+func shuffleCandidates(tx *gorm.DB, teamName string, candidates []entities.User) []entities.User {
+	shuffled := make([]entities.User, len(candidates))
+	copy(shuffled, candidates)
+
+	var seed string
+	var seeded bool
+	if tx != nil && tx.Statement != nil && tx.Statement.Context != nil {
+		seed, seeded = tx.Statement.Context.Value(selectionSeedKey{}).(string)
+	}
+	if !seeded {
+		rand.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		return shuffled
+	}
+
+	sort.Slice(shuffled, func(i, j int) bool {
+		return shuffled[i].UserID < shuffled[j].UserID
+	})
+
+	hash := fnv.New64a()
+	for _, part := range append([]string{seed, teamName}, appendUserIDs(nil, shuffled)...) {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return shuffled
+}
+
+// openReviewCounts returns the number of OPEN pull requests each user is reviewing.
+// Users without open reviews are absent from the result.
+func openReviewCounts(tx *gorm.DB, userIDs []string) (map[string]int64, error) {
+	var loads []struct {
+		UserID      string
+		OpenReviews int64
+	}
+	err := tx.Table("pull_request_reviewers").
+		Select("pull_request_reviewers.user_id, COUNT(*) AS open_reviews").
+		Joins("JOIN pull_requests ON pull_requests.pull_request_id = pull_request_reviewers.pull_request_id").
+		Where("pull_requests.status = ? AND pull_request_reviewers.user_id IN ?", entities.PRStatusOpen, userIDs).
+		Group("pull_request_reviewers.user_id").
+		Scan(&loads).Error
+	if err != nil {
+		return nil, err
+	}
+
+	openReviews := make(map[string]int64, len(loads))
+	for _, load := range loads {
+		openReviews[load.UserID] = load.OpenReviews
+	}
+
+	return openReviews, nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestRotateAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		cursor     string
+		count      int
+		want       []string
+	}{
+		{name: "empty cursor starts at first", candidates: []string{"u3", "u1", "u2"}, cursor: "", count: 2, want: []string{"u1", "u2"}},
+		{name: "starts after cursor", candidates: []string{"u1", "u2", "u3"}, cursor: "u1", count: 2, want: []string{"u2", "u3"}},
+		{name: "wraps around", candidates: []string{"u1", "u2", "u3"}, cursor: "u2", count: 2, want: []string{"u3", "u1"}},
+		{name: "cursor at last wraps to first", candidates: []string{"u1", "u2", "u3"}, cursor: "u3", count: 1, want: []string{"u1"}},
+		{name: "cursor not among candidates", candidates: []string{"u1", "u3", "u5"}, cursor: "u2", count: 2, want: []string{"u3", "u5"}},
+		{name: "cursor after all candidates", candidates: []string{"u1", "u2"}, cursor: "u9", count: 1, want: []string{"u1"}},
+		{name: "count capped by candidates", candidates: []string{"u1", "u2"}, cursor: "u1", count: 5, want: []string{"u2", "u1"}},
+		{name: "zero count", candidates: []string{"u1", "u2"}, cursor: "", count: 0, want: []string{}},
+		{name: "no candidates", candidates: []string{}, cursor: "u1", count: 2, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := make([]entities.User, len(tt.candidates))
+			for i, userID := range tt.candidates {
+				candidates[i] = entities.User{UserID: userID}
+			}
+
+			selected := rotateAfter(candidates, tt.cursor, tt.count)
+
+			got := make([]string, len(selected))
+			for i, user := range selected {
+				got[i] = user.UserID
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("rotateAfter(%v, %q, %d) = %v, want %v", tt.candidates, tt.cursor, tt.count, got, tt.want)
+			}
+			for i, user := range candidates {
+				if user.UserID != tt.candidates[i] {
+					t.Fatalf("rotateAfter reordered its input")
+				}
+			}
+		})
+	}
+}
+
+func TestSeededSelectionIsRepeatable(t *testing.T) {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	candidates := make([]entities.User, 20)
+	for i := range candidates {
+		candidates[i] = entities.User{UserID: fmt.Sprintf("u%02d", i)}
+	}
+	reversed := slices.Clone(candidates)
+	slices.Reverse(reversed)
+
+	pick := func(seed string, candidates []entities.User) []string {
+		selected, err := NewRandomSelector().Select(withSelectionSeed(db, seed), "backend", candidates, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return appendUserIDs(nil, selected)
+	}
+
+	first := pick("pr-1/u01", candidates)
+	if again := pick("pr-1/u01", reversed); !slices.Equal(first, again) {
+		t.Errorf("same seed picked %v, then %v", first, again)
+	}
+
+	differs := false
+	for i := 0; i < 10 && !differs; i++ {
+		differs = !slices.Equal(first, pick(fmt.Sprintf("pr-%d/u01", i+2), candidates))
+	}
+	if !differs {
+		t.Errorf("every seed picked %v", first)
+	}
+}
+
+// Pull requests created at the same time must still walk the rotation in
+// turn: the cursor is locked, so no two of them start from the same position.
+func TestRoundRobinConcurrentCreates(t *testing.T) {
+	db := openTestDB(t)
+	reviewers := []string{"r1", "r2", "r3", "r4"}
+
+	mustCreate(t, db,
+		&entities.Team{TeamName: "rotation", ReviewerStrategy: entities.ReviewerStrategyRoundRobin, MinReviewers: 1, MaxReviewers: 1},
+		&entities.User{UserID: "author", Username: "author", TeamName: "rotation", IsActive: true},
+		&entities.TeamMembership{TeamName: "rotation", UserID: "author", Role: entities.MembershipRoleMember},
+	)
+	for _, userID := range reviewers {
+		mustCreate(t, db,
+			&entities.User{UserID: userID, Username: userID, TeamName: "rotation", IsActive: true},
+			&entities.TeamMembership{TeamName: "rotation", UserID: userID, Role: entities.MembershipRoleMember},
+		)
+	}
+
+	service := NewPullRequestService(db, nil)
+	const rounds = 3
+	var wg sync.WaitGroup
+	errs := make(chan error, rounds*len(reviewers))
+	for i := 0; i < rounds*len(reviewers); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prID := fmt.Sprintf("pr-%02d", i)
+			_, err := service.Create(&entities.PullRequest{PullRequestID: prID, PullRequestName: prID, AuthorID: "author"})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Create() error: %v", err)
+		}
+	}
+
+	var picks []struct {
+		UserID string
+		Picks  int
+	}
+	err := db.Model(&entities.PullRequestReviewer{}).
+		Select("user_id, COUNT(*) AS picks").
+		Group("user_id").
+		Order("user_id").
+		Scan(&picks).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(picks) != len(reviewers) {
+		t.Fatalf("picked reviewers = %v, want each of %v", picks, reviewers)
+	}
+	for _, pick := range picks {
+		if pick.Picks != rounds {
+			t.Errorf("%s picked %d times, want %d", pick.UserID, pick.Picks, rounds)
+		}
+	}
+
+	var cursor entities.ReviewerRotationCursor
+	if err := db.Where("team_name = ?", "rotation").First(&cursor).Error; err != nil {
+		t.Fatal(err)
+	}
+	if cursor.LastUserID != "r4" {
+		t.Errorf("cursor = %q, want r4", cursor.LastUserID)
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const staleReviewBatchSize = 100
+
+// StaleReviewWorker periodically looks for reviews that are past the review SLA
+// of the author's team. Depending on the team's stale_action it flags them or
+// reassigns them; a reassignment without an eligible replacement falls back to flagging.
+type StaleReviewWorker struct {
+	db        *gorm.DB
+	logger    *slog.Logger
+	assigner  *reviewerAssigner
+	interval  time.Duration
+	mu        sync.Mutex
+	isRunning bool
+	stopCh    chan struct{}
+}
+
+func NewStaleReviewWorker(db *gorm.DB, logger *slog.Logger, selectors map[string]interfaces.ReviewerSelector, interval time.Duration) *StaleReviewWorker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &StaleReviewWorker{
+		db:       db,
+		logger:   logger,
+		assigner: newReviewerAssigner(selectors),
+		interval: interval,
+	}
+}
+
+func (w *StaleReviewWorker) Start(ctx context.Context) error {
+	w.mu.Lock()
+	if w.isRunning {
+		w.mu.Unlock()
+		return fmt.Errorf("stale review worker is already running")
+	}
+	w.isRunning = true
+	w.stopCh = make(chan struct{})
+	stopCh := w.stopCh
+	w.mu.Unlock()
+
+	w.logger.Info("Starting stale review worker", "interval", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-stopCh:
+			return nil
+		case <-ticker.C:
+			if err := w.ProcessStale(); err != nil {
+				w.logger.Error(fmt.Sprintf("Failed to process stale reviews: %v", err))
+			}
+		}
+	}
+}
+
+func (w *StaleReviewWorker) Stop(ctx context.Context, timeout time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.isRunning {
+		return nil
+	}
+
+	close(w.stopCh)
+	w.isRunning = false
+	w.logger.Info("Stale review worker stopped")
+	return nil
+}
+
+// ProcessStale handles every stale review that has not been dealt with yet.
+// Reviews are read in pages keyed by (due_at, pull_request_id, user_id), so
+// reviews of reassign teams that stay flagged for lack of a replacement do
+// not keep newer stale reviews out of the batch.
+func (w *StaleReviewWorker) ProcessStale() error {
+	now := time.Now()
+	var after *entities.StaleReview
+	for {
+		// Flagged reviews of teams that only flag need nothing more.
+		query := staleReviewsQuery(w.db, now).
+			Where("NOT (t.stale_action = ? AND prr.stale_flagged_at IS NOT NULL)", entities.StaleActionFlag)
+		if after != nil {
+			query = query.Where("(prr.assigned_at + t.review_sla_hours * INTERVAL '1 hour', prr.pull_request_id, prr.user_id) > (?, ?, ?)",
+				after.DueAt, after.PullRequestID, after.UserID)
+		}
+
+		stale := []entities.StaleReview{}
+		if err := query.Limit(staleReviewBatchSize).Scan(&stale).Error; err != nil {
+			return err
+		}
+
+		for _, review := range stale {
+			if err := w.handle(review); err != nil {
+				w.logger.Error(fmt.Sprintf("Failed to handle stale review of %s on PR %s: %v",
+					review.UserID, review.PullRequestID, err))
+			}
+		}
+
+		if len(stale) < staleReviewBatchSize {
+			return nil
+		}
+		after = &stale[len(stale)-1]
+	}
+}
+
+func (w *StaleReviewWorker) handle(review entities.StaleReview) error {
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		pr, err := lockPullRequest(tx, review.PullRequestID)
+		if err != nil {
+			return err
+		}
+
+		// The review may have been decided or reassigned since it was listed.
+		index := slices.IndexFunc(pr.AssignedReviewers, func(reviewer entities.PullRequestReviewer) bool {
+			return reviewer.UserID == review.UserID
+		})
+		if pr.Status != entities.PRStatusOpen || index < 0 ||
+			pr.AssignedReviewers[index].Decision != entities.ReviewDecisionPending {
+			return nil
+		}
+
+		if review.StaleAction == entities.StaleActionReassign {
+			added, err := w.assigner.replace(tx, pr, review.UserID, "", reasonReviewSLA)
+			if err == nil {
+				w.logger.Info("Reassigned stale review", "pull_request_id", review.PullRequestID,
+					"old_reviewer", review.UserID, "new_reviewer", added[0].UserID, "due_at", review.DueAt)
+				return syncPendingAssignment(tx, review.PullRequestID)
+			}
+			if !errors.Is(err, entities.ErrNoReplacement) {
+				return err
+			}
+		}
+
+		if pr.AssignedReviewers[index].StaleFlaggedAt != nil {
+			return nil
+		}
+
+		if review.StaleAction == entities.StaleActionReassign {
+			w.logger.Warn("No replacement for stale review, flagging it instead",
+				"pull_request_id", review.PullRequestID, "reviewer", review.UserID)
+		}
+
+		err = tx.Model(&entities.PullRequestReviewer{}).
+			Where("pull_request_id = ? AND user_id = ?", review.PullRequestID, review.UserID).
+			Update("stale_flagged_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		w.logger.Info("Flagged stale review", "pull_request_id", review.PullRequestID,
+			"reviewer", review.UserID, "due_at", review.DueAt)
+		return nil
+	})
+}
+
+// staleReviewsQuery selects pending reviews of OPEN pull requests whose SLA
+// ended before now as entities.StaleReview rows, most overdue first. Ties are
+// broken by pull request and reviewer so that the order can be paged through.
+func staleReviewsQuery(tx *gorm.DB, now time.Time) *gorm.DB {
+	return tx.Table("pull_request_reviewers prr").
+		Select("prr.pull_request_id, prr.user_id, t.team_name, prr.assigned_at, "+
+			"prr.assigned_at + t.review_sla_hours * INTERVAL '1 hour' AS due_at, "+
+			"prr.stale_flagged_at AS flagged_at, t.stale_action").
+		Joins("JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id").
+		Joins("JOIN users a ON a.user_id = pr.author_id").
+		Joins("JOIN teams t ON t.team_name = a.team_name").
+		Where("pr.status = ? AND prr.decision = ? AND t.review_sla_hours > 0",
+			entities.PRStatusOpen, entities.ReviewDecisionPending).
+		Where("prr.assigned_at + t.review_sla_hours * INTERVAL '1 hour' <= ?", now).
+		Order("due_at, prr.pull_request_id, prr.user_id")
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"CodeRewievService/internal/entities"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// Reviews of a reassign team that have no replacement stay stale and flagged.
+// They must not keep newer stale reviews from being reassigned.
+func TestProcessStaleReassignsPastUnreplaceableReviews(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+
+	mustCreate(t, db,
+		&entities.Team{TeamName: "solo", ReviewSLAHours: 1, StaleAction: entities.StaleActionReassign},
+		&entities.Team{TeamName: "backend", ReviewSLAHours: 1, StaleAction: entities.StaleActionReassign},
+		&[]entities.User{
+			{UserID: "solo-author", Username: "solo-author", TeamName: "solo", IsActive: true},
+			{UserID: "solo-reviewer", Username: "solo-reviewer", TeamName: "solo", IsActive: true},
+			{UserID: "be-author", Username: "be-author", TeamName: "backend", IsActive: true},
+			{UserID: "be-reviewer", Username: "be-reviewer", TeamName: "backend", IsActive: true},
+			{UserID: "be-spare", Username: "be-spare", TeamName: "backend", IsActive: true},
+		},
+		&[]entities.TeamMembership{
+			{TeamName: "solo", UserID: "solo-author", Role: entities.MembershipRoleMember},
+			{TeamName: "solo", UserID: "solo-reviewer", Role: entities.MembershipRoleMember},
+			{TeamName: "backend", UserID: "be-author", Role: entities.MembershipRoleMember},
+			{TeamName: "backend", UserID: "be-reviewer", Role: entities.MembershipRoleMember},
+			{TeamName: "backend", UserID: "be-spare", Role: entities.MembershipRoleMember},
+		},
+	)
+
+	// A full batch of older stale reviews that an earlier run already flagged
+	// because the solo team has nobody else to hand them to.
+	flaggedAt := now.Add(-time.Hour)
+	for i := 0; i < staleReviewBatchSize+1; i++ {
+		prID := fmt.Sprintf("solo-%03d", i)
+		mustCreate(t, db,
+			&entities.PullRequest{PullRequestID: prID, PullRequestName: prID, AuthorID: "solo-author", Status: entities.PRStatusOpen, RequiredReviewers: 1},
+			&entities.PullRequestReviewer{PullRequestID: prID, UserID: "solo-reviewer", AssignedAt: now.Add(-3 * time.Hour), StaleFlaggedAt: &flaggedAt},
+		)
+	}
+	mustCreate(t, db,
+		&entities.PullRequest{PullRequestID: "be-1", PullRequestName: "be-1", AuthorID: "be-author", Status: entities.PRStatusOpen, RequiredReviewers: 1},
+		&entities.PullRequestReviewer{PullRequestID: "be-1", UserID: "be-reviewer", AssignedAt: now.Add(-2 * time.Hour)},
+	)
+
+	worker := NewStaleReviewWorker(db, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, time.Minute)
+	if err := worker.ProcessStale(); err != nil {
+		t.Fatalf("ProcessStale() error: %v", err)
+	}
+
+	var reviewers []string
+	if err := db.Model(&entities.PullRequestReviewer{}).Where("pull_request_id = ?", "be-1").Pluck("user_id", &reviewers).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(reviewers) != 1 || reviewers[0] != "be-spare" {
+		t.Errorf("be-1 reviewers = %v, want [be-spare]", reviewers)
+	}
+
+	var unreplaced int64
+	err := db.Model(&entities.PullRequestReviewer{}).
+		Where("user_id = ? AND stale_flagged_at IS NOT NULL", "solo-reviewer").
+		Count(&unreplaced).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unreplaced != staleReviewBatchSize+1 {
+		t.Errorf("flagged solo reviews = %d, want %d", unreplaced, staleReviewBatchSize+1)
+	}
+}
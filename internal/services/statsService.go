@@ -5,6 +5,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// teamMember matches users with any membership in a team. Pull request counts
+// use the author's home team instead.
+const teamMember = "user_id IN (SELECT user_id FROM team_memberships WHERE team_name = ?)"
+
 type StatsService struct {
 	db *gorm.DB
 }
@@ -19,11 +23,11 @@ func (s *StatsService) GetTeamStats(teamName string) (*entities.TeamStats, error
 	var stats entities.TeamStats
 	stats.TeamName = teamName
 
-	if err := s.db.Model(&entities.User{}).Where("team_name = ?", teamName).Count(&stats.TotalMembers).Error; err != nil {
+	if err := s.db.Model(&entities.TeamMembership{}).Where("team_name = ?", teamName).Count(&stats.TotalMembers).Error; err != nil {
 		return nil, err
 	}
 
-	if err := s.db.Model(&entities.User{}).Where("team_name = ? AND is_active = true", teamName).Count(&stats.ActiveMembers).Error; err != nil {
+	if err := s.db.Model(&entities.User{}).Where(teamMember+" AND is_active = true", teamName).Count(&stats.ActiveMembers).Error; err != nil {
 		return nil, err
 	}
 
@@ -69,7 +73,7 @@ func (s *StatsService) GetTeamStats(teamName string) (*entities.TeamStats, error
 
 func (s *StatsService) GetUserStats(teamName string) ([]entities.UserStats, error) {
 	var users []entities.User
-	if err := s.db.Where("team_name = ?", teamName).Find(&users).Error; err != nil {
+	if err := s.db.Where(teamMember, teamName).Find(&users).Error; err != nil {
 		return nil, err
 	}
 
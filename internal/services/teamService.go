@@ -2,23 +2,28 @@ package services
 
 import (
 	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TeamService struct {
-	db     *gorm.DB
-	logger *slog.Logger
+	db       *gorm.DB
+	logger   *slog.Logger
+	assigner *reviewerAssigner
 }
 
-func NewTeamService(db *gorm.DB, logger *slog.Logger) *TeamService {
+func NewTeamService(db *gorm.DB, logger *slog.Logger, selectors map[string]interfaces.ReviewerSelector) *TeamService {
 	return &TeamService{
-		db:     db,
-		logger: logger,
+		db:       db,
+		logger:   logger,
+		assigner: newReviewerAssigner(selectors),
 	}
 }
 
@@ -31,6 +36,10 @@ func (ts *TeamService) Add(team *entities.Team) error {
 		return errors.New("team name cannot be empty")
 	}
 
+	if team.ReviewerStrategy != "" && !ts.assigner.hasStrategy(team.ReviewerStrategy) {
+		return fmt.Errorf("%w: unknown reviewer strategy %q", entities.ErrInvalidTeamSettings, team.ReviewerStrategy)
+	}
+
 	var existingTeam entities.Team
 	result := ts.db.Where("team_name = ?", team.TeamName).First(&existingTeam)
 	if result.Error == nil {
@@ -44,21 +53,526 @@ func (ts *TeamService) Add(team *entities.Team) error {
 			return err
 		}
 
-		for _, member := range team.Members {
-			user := &entities.User{
-				UserID:   member.UserID,
-				Username: member.Username,
-				TeamName: team.TeamName,
-				IsActive: member.IsActive,
+		_, err := saveMembers(tx, team.TeamName, team.Members)
+		return err
+	})
+}
+
+// AddMembers adds users to an existing team. Unknown users are created with it
+// as their home team, users without a home team get it as one. Users of other
+// teams keep their home team and get an extra membership. The role of a user
+// already in the team is updated.
+func (ts *TeamService) AddMembers(teamName string, members []entities.User) ([]entities.User, error) {
+	if teamName == "" {
+		return nil, errors.New("team name cannot be empty")
+	}
+
+	var saved []entities.User
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		var team entities.Team
+		result := tx.Where("team_name = ?", teamName).First(&team)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return entities.ErrTeamNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		var err error
+		saved, err = saveMembers(tx, teamName, members)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// RemoveMember takes a user out of the team. When it was their home team,
+// another of their teams becomes the home team; a user without memberships
+// stays in the system without a team until they are added to one again. Their
+// open reviews of the team's pull requests are kept or reassigned according to
+// the request.
+func (ts *TeamService) RemoveMember(request *entities.RequestRemoveTeamMember) (*entities.MembershipChange, error) {
+	if request == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	reviews, err := membershipPolicy(request.Reviews)
+	if err != nil {
+		return nil, err
+	}
+
+	var change *entities.MembershipChange
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, request.UserID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := lockMembership(tx, request.TeamName, user.UserID); err != nil {
+			return err
+		}
+
+		change, err = ts.leaveTeam(tx, user, request.TeamName, reviews)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("team_name = ? AND user_id = ?", request.TeamName, user.UserID).
+			Delete(&entities.TeamMembership{}).Error; err != nil {
+			return err
+		}
+
+		if user.TeamName == request.TeamName {
+			// Teams the user reviews for are preferred as the new home team.
+			var homeTeams []string
+			err := tx.Model(&entities.TeamMembership{}).
+				Where("user_id = ?", user.UserID).
+				Order("role = '"+entities.MembershipRoleObserver+"'").
+				Order("created_at").
+				Limit(1).
+				Pluck("team_name", &homeTeams).Error
+			if err != nil {
+				return err
 			}
 
-			if err := tx.Save(user).Error; err != nil {
+			var homeTeam *string
+			user.TeamName = ""
+			if len(homeTeams) > 0 {
+				homeTeam = &homeTeams[0]
+				user.TeamName = homeTeams[0]
+			}
+			if err := tx.Model(user).Update("team_name", homeTeam).Error; err != nil {
 				return err
 			}
 		}
+		change.User = *user
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// MoveMember moves a user's membership, with its role, from one team to
+// another. Their open reviews of the old team's pull requests are kept or
+// reassigned within the old team. When the move changes the home team and the
+// policy for authored pull requests is reassign, reviewers the old team picked
+// for the user's open pull requests are replaced from the new team; reviewers
+// requested by name are kept either way.
+func (ts *TeamService) MoveMember(request *entities.RequestMoveTeamMember) (*entities.MembershipChange, error) {
+	if request == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	reviews, err := membershipPolicy(request.Reviews)
+	if err != nil {
+		return nil, err
+	}
+	authoredPRs, err := membershipPolicy(request.AuthoredPRs)
+	if err != nil {
+		return nil, err
+	}
+
+	var change *entities.MembershipChange
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		var team entities.Team
+		result := tx.Where("team_name = ?", request.TeamName).First(&team)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return entities.ErrTeamNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		user, err := lockUser(tx, request.UserID)
+		if err != nil {
+			return err
+		}
+
+		fromTeam := request.FromTeamName
+		if fromTeam == "" {
+			fromTeam = user.TeamName
+		}
+		if fromTeam == "" {
+			return fmt.Errorf("%w: %s is not in a team, add them instead", entities.ErrInvalidMembershipChange, user.UserID)
+		}
+
+		membership, err := lockMembership(tx, fromTeam, user.UserID)
+		if err != nil {
+			return err
+		}
+
+		var existing int64
+		err = tx.Model(&entities.TeamMembership{}).
+			Where("team_name = ? AND user_id = ?", request.TeamName, user.UserID).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: %s is already in team %s", entities.ErrInvalidMembershipChange, user.UserID, request.TeamName)
+		}
+
+		// Reviews are reassigned while the user is still in the old team, so
+		// replacements come from there.
+		change, err = ts.leaveTeam(tx, user, fromTeam, reviews)
+		if err != nil {
+			return err
+		}
+		change.ToTeam = request.TeamName
+
+		if err := tx.Delete(membership).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&entities.TeamMembership{
+			TeamName: request.TeamName,
+			UserID:   user.UserID,
+			Role:     membership.Role,
+		}).Error; err != nil {
+			return err
+		}
+
+		if user.TeamName == fromTeam {
+			if err := tx.Model(user).Update("team_name", request.TeamName).Error; err != nil {
+				return err
+			}
+			user.TeamName = request.TeamName
+
+			if authoredPRs == entities.MembershipPolicyReassign {
+				change.AuthoredPRs, err = ts.replaceTeamReviewers(tx, user.UserID, fromTeam, request.TeamName)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		change.User = *user
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// leaveTeam prepares user, who must be locked, for leaving teamName. The lead
+// of a team cannot leave it. With the reassign policy the user is replaced on
+// every OPEN pull request of the team they review.
+func (ts *TeamService) leaveTeam(tx *gorm.DB, user *entities.User, teamName string, reviews string) (*entities.MembershipChange, error) {
+	change := &entities.MembershipChange{
+		FromTeam:    teamName,
+		ReviewedPRs: []entities.PullRequestReassignment{},
+		AuthoredPRs: []entities.AuthoredPRUpdate{},
+	}
+
+	var leads int64
+	err := tx.Model(&entities.Team{}).
+		Where("team_name = ? AND lead_user_id = ?", teamName, user.UserID).
+		Count(&leads).Error
+	if err != nil {
+		return nil, err
+	}
+	if leads > 0 {
+		return nil, fmt.Errorf("%w: %s is the lead of team %s, change lead_user_id first",
+			entities.ErrInvalidMembershipChange, user.UserID, teamName)
+	}
+
+	if reviews != entities.MembershipPolicyReassign {
+		return change, nil
+	}
+
+	var prs []entities.PullRequest
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("AssignedReviewers").
+		Preload("Author").
+		Where("status = ? AND pull_request_id IN (SELECT pull_request_id FROM pull_request_reviewers WHERE user_id = ?)",
+			entities.PRStatusOpen, user.UserID).
+		Where("author_id IN (SELECT user_id FROM users WHERE team_name = ?)", teamName).
+		Order("pull_request_id").
+		Find(&prs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	change.ReviewedPRs, err = replaceReviewers(tx, ts.assigner, prs, []string{user.UserID},
+		entities.AssignmentEventUnassigned, reasonLeftTeam)
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// replaceTeamReviewers removes members of oldTeam picked by the team or
+// fallback rules from the OPEN pull requests of authorID and fills the freed
+// slots from newTeam, the author's current home team. Reviewers who also
+// review for newTeam are kept.
+func (ts *TeamService) replaceTeamReviewers(tx *gorm.DB, authorID string, oldTeam string, newTeam string) ([]entities.AuthoredPRUpdate, error) {
+	var prs []entities.PullRequest
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("AssignedReviewers").
+		Preload("Author").
+		Where("status = ? AND author_id = ?", entities.PRStatusOpen, authorID).
+		Order("pull_request_id").
+		Find(&prs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	updates := []entities.AuthoredPRUpdate{}
+	for i := range prs {
+		pr := &prs[i]
+
+		var picked []string
+		for _, reviewer := range pr.AssignedReviewers {
+			if reviewer.Source == entities.ReviewerSourceTeam || reviewer.Source == entities.ReviewerSourceFallback {
+				picked = append(picked, reviewer.UserID)
+			}
+		}
+		if len(picked) == 0 {
+			continue
+		}
+
+		var removed []string
+		err := tx.Model(&entities.TeamMembership{}).
+			Where("team_name = ? AND user_id IN ?", oldTeam, picked).
+			Where("user_id NOT IN (?)", tx.Model(&entities.User{}).
+				Select("user_id").
+				Where(reviewsForTeam, newTeam, entities.MembershipRoleObserver)).
+			Order("user_id").
+			Pluck("user_id", &removed).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(removed) == 0 {
+			continue
+		}
+
+		if err := tx.Where("pull_request_id = ? AND user_id IN ?", pr.PullRequestID, removed).
+			Delete(&entities.PullRequestReviewer{}).Error; err != nil {
+			return nil, err
+		}
+
+		events := make([]entities.ReviewerAssignmentEvent, len(removed))
+		for n, userID := range removed {
+			events[n] = entities.ReviewerAssignmentEvent{
+				PullRequestID: pr.PullRequestID,
+				EventType:     entities.AssignmentEventUnassigned,
+				UserID:        userID,
+				Reason:        reasonAuthorMoved,
+			}
+		}
+		if err := recordEvents(tx, events); err != nil {
+			return nil, err
+		}
+
+		kept := []string{}
+		for _, userID := range reviewerUserIDs(pr.AssignedReviewers) {
+			if !slices.Contains(removed, userID) {
+				kept = append(kept, userID)
+			}
+		}
+
+		excluded := append([]string{pr.AuthorID}, kept...)
+		excluded = append(excluded, removed...)
+		if _, err := ts.assigner.fill(tx, pr, pr.RequiredReviewers-len(kept), excluded, reasonAuthorMoved); err != nil {
+			return nil, err
+		}
+
+		var reviewers []entities.PullRequestReviewer
+		if err := tx.Where("pull_request_id = ?", pr.PullRequestID).Find(&reviewers).Error; err != nil {
+			return nil, err
+		}
+
+		added := []string{}
+		for _, userID := range reviewerUserIDs(reviewers) {
+			if !slices.Contains(kept, userID) {
+				added = append(added, userID)
+			}
+		}
+
+		if err := syncPendingAssignment(tx, pr.PullRequestID); err != nil {
+			return nil, err
+		}
+
+		updates = append(updates, entities.AuthoredPRUpdate{
+			PullRequestID:    pr.PullRequestID,
+			RemovedReviewers: removed,
+			AddedReviewers:   added,
+		})
+	}
+
+	return updates, nil
+}
+
+// saveMembers creates or updates members and their memberships in teamName
+// and returns them as saved. See AddMembers for how home teams are chosen.
+// A member saved with the lead role becomes the team's lead_user_id; the
+// current lead cannot be given another role here.
+func saveMembers(tx *gorm.DB, teamName string, members []entities.User) ([]entities.User, error) {
+	var team entities.Team
+	if err := tx.Select("lead_user_id").Where("team_name = ?", teamName).First(&team).Error; err != nil {
+		return nil, err
+	}
+	lead := team.LeadUserID
+
+	saved := make([]entities.User, 0, len(members))
+	for _, member := range members {
+		if member.UserID == "" {
+			return nil, fmt.Errorf("%w: user_id cannot be empty", entities.ErrInvalidMembershipChange)
+		}
+
+		role := member.Role
+		if role == "" {
+			role = entities.MembershipRoleMember
+		}
+		if role != entities.MembershipRoleMember && role != entities.MembershipRoleLead && role != entities.MembershipRoleObserver {
+			return nil, fmt.Errorf("%w: role must be %q, %q or %q", entities.ErrInvalidMembershipChange,
+				entities.MembershipRoleMember, entities.MembershipRoleLead, entities.MembershipRoleObserver)
+		}
+		if role == entities.MembershipRoleLead {
+			lead = &member.UserID
+		} else if lead != nil && *lead == member.UserID {
+			return nil, fmt.Errorf("%w: %s is the lead of team %s, change lead_user_id first",
+				entities.ErrInvalidMembershipChange, member.UserID, teamName)
+		}
+
+		user := entities.User{
+			UserID:   member.UserID,
+			Username: member.Username,
+			TeamName: teamName,
+			IsActive: member.IsActive,
+			Role:     role,
+		}
+
+		var existing entities.User
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", member.UserID).
+			First(&existing)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			if err := tx.Create(&user).Error; err != nil {
+				return nil, err
+			}
+		} else if result.Error != nil {
+			return nil, result.Error
+		} else {
+			// Activity is changed through /users/setIsActive only, which also
+			// reassigns the reviews of deactivated users.
+			if existing.TeamName != "" {
+				user.TeamName = existing.TeamName
+			}
+			if user.Username == "" {
+				user.Username = existing.Username
+			}
+			user.IsActive = existing.IsActive
+			user.MaxOpenReviews = existing.MaxOpenReviews
+
+			err := tx.Model(&existing).Updates(map[string]interface{}{
+				"username":  user.Username,
+				"team_name": user.TeamName,
+			}).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "team_name"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		}).Create(&entities.TeamMembership{
+			TeamName: teamName,
+			UserID:   user.UserID,
+			Role:     role,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+
+		saved = append(saved, user)
+	}
+
+	if lead != nil && (team.LeadUserID == nil || *team.LeadUserID != *lead) {
+		err := tx.Model(&entities.Team{}).
+			Where("team_name = ?", teamName).
+			Update("lead_user_id", *lead).Error
+		if err != nil {
+			return nil, err
+		}
+		if err := syncLeadRole(tx, teamName, lead); err != nil {
+			return nil, err
+		}
+	}
+
+	return saved, nil
+}
+
+// syncLeadRole gives leadUserID the lead role in teamName and makes any
+// other lead of the team a member, so that the lead role always matches the
+// team's lead_user_id. A nil leadUserID leaves the team without a lead.
+func syncLeadRole(tx *gorm.DB, teamName string, leadUserID *string) error {
+	demote := tx.Model(&entities.TeamMembership{}).
+		Where("team_name = ? AND role = ?", teamName, entities.MembershipRoleLead)
+	if leadUserID != nil {
+		demote = demote.Where("user_id <> ?", *leadUserID)
+	}
+	if err := demote.Update("role", entities.MembershipRoleMember).Error; err != nil {
+		return err
+	}
+
+	if leadUserID == nil {
+		return nil
+	}
+
+	return tx.Model(&entities.TeamMembership{}).
+		Where("team_name = ? AND user_id = ?", teamName, *leadUserID).
+		Update("role", entities.MembershipRoleLead).Error
+}
+
+func lockUser(tx *gorm.DB, userID string) (*entities.User, error) {
+	var user entities.User
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		First(&user)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", entities.ErrUserNotFound, userID)
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+func lockMembership(tx *gorm.DB, teamName string, userID string) (*entities.TeamMembership, error) {
+	var membership entities.TeamMembership
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("team_name = ? AND user_id = ?", teamName, userID).
+		First(&membership)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s is not in team %s", entities.ErrNotTeamMember, userID, teamName)
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &membership, nil
+}
+
+// membershipPolicy validates a policy for open pull requests of a leaving
+// member. Reassigning is the default.
+func membershipPolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return entities.MembershipPolicyReassign, nil
+	case entities.MembershipPolicyKeep, entities.MembershipPolicyReassign:
+		return policy, nil
+	}
+
+	return "", fmt.Errorf("%w: policy must be %q or %q", entities.ErrInvalidMembershipChange,
+		entities.MembershipPolicyKeep, entities.MembershipPolicyReassign)
 }
 
 func (ts *TeamService) Get(teamName string) (*entities.Team, error) {
@@ -87,23 +601,240 @@ func (ts *TeamService) Get(teamName string) (*entities.Team, error) {
 
 	fmt.Printf("Found team: %+v\n", team)
 
+	var memberships []entities.TeamMembership
+	if err := ts.db.Where("team_name = ?", team.TeamName).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]string, len(memberships))
+	userIDs := make([]string, len(memberships))
+	for i, membership := range memberships {
+		roles[membership.UserID] = membership.Role
+		userIDs[i] = membership.UserID
+	}
+
 	var users []entities.User
-	if err := ts.db.Debug().Where("team_name = ?", team.TeamName).Find(&users).Error; err != nil {
+	if err := ts.db.Debug().Where("user_id IN ?", userIDs).Find(&users).Error; err != nil {
 		fmt.Printf("Error loading users: %v\n", err)
 		return nil, err
 	}
 
+	// Members are listed as members of the requested team, whatever their home team is.
+	for i := range users {
+		users[i].TeamName = team.TeamName
+		users[i].Role = roles[users[i].UserID]
+	}
+
 	fmt.Printf("Found %d users for team\n", len(users))
 
+	if err := attachSkills(ts.db, users); err != nil {
+		return nil, err
+	}
+
 	team.Members = users
 
 	return &team, nil
 }
 
-func (ts *TeamService) MassDeactivateTeamUsers(teamName string) error {
+func (ts *TeamService) GetSettings(teamName string) (*entities.TeamSettings, error) {
+	if teamName == "" {
+		return nil, errors.New("team name cannot be empty")
+	}
+
+	var team entities.Team
+	result := ts.db.Where("team_name = ?", teamName).First(&team)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrTeamNotFound
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	settings := team.Settings()
+	fallbacks, err := fallbackTeams(ts.db, teamName)
+	if err != nil {
+		return nil, err
+	}
+	settings.FallbackTeams = fallbacks
+
+	return &settings, nil
+}
+
+func (ts *TeamService) UpdateSettings(request *entities.RequestUpdateTeamSettings) (*entities.TeamSettings, error) {
+	if request == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	if request.TeamName == "" {
+		return nil, errors.New("team name cannot be empty")
+	}
+
+	var team entities.Team
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("team_name = ?", request.TeamName).
+			First(&team)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return entities.ErrTeamNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		if request.ReviewerStrategy != nil {
+			team.ReviewerStrategy = *request.ReviewerStrategy
+		}
+		if request.MinReviewers != nil {
+			team.MinReviewers = *request.MinReviewers
+		}
+		if request.MaxReviewers != nil {
+			team.MaxReviewers = *request.MaxReviewers
+		}
+		if request.MaxOpenReviews != nil {
+			team.MaxOpenReviews = *request.MaxOpenReviews
+		}
+		if request.MinApprovals != nil {
+			team.MinApprovals = *request.MinApprovals
+		}
+		if request.BlockOnChangesRequested != nil {
+			team.BlockOnChangesRequested = *request.BlockOnChangesRequested
+		}
+		if request.RequireLeadApproval != nil {
+			team.RequireLeadApproval = *request.RequireLeadApproval
+		}
+		if request.ReviewSLAHours != nil {
+			team.ReviewSLAHours = *request.ReviewSLAHours
+		}
+		if request.StaleAction != nil {
+			team.StaleAction = *request.StaleAction
+		}
+		if request.LeadUserID != nil {
+			team.LeadUserID = request.LeadUserID
+			if *request.LeadUserID == "" {
+				team.LeadUserID = nil
+			}
+		}
+
+		if !ts.assigner.hasStrategy(team.ReviewerStrategy) {
+			return fmt.Errorf("%w: unknown reviewer strategy %q", entities.ErrInvalidTeamSettings, team.ReviewerStrategy)
+		}
+		if team.MinReviewers < 0 || team.MaxReviewers < 1 || team.MinReviewers > team.MaxReviewers {
+			return fmt.Errorf("%w: expected 0 <= min_reviewers <= max_reviewers and max_reviewers >= 1",
+				entities.ErrInvalidTeamSettings)
+		}
+		if team.MaxOpenReviews < 0 {
+			return fmt.Errorf("%w: max_open_reviews cannot be negative", entities.ErrInvalidTeamSettings)
+		}
+		if team.MinApprovals < 0 {
+			return fmt.Errorf("%w: min_approvals cannot be negative", entities.ErrInvalidTeamSettings)
+		}
+		if team.ReviewSLAHours < 0 {
+			return fmt.Errorf("%w: review_sla_hours cannot be negative", entities.ErrInvalidTeamSettings)
+		}
+		if team.StaleAction != entities.StaleActionFlag && team.StaleAction != entities.StaleActionReassign {
+			return fmt.Errorf("%w: stale_action must be %q or %q", entities.ErrInvalidTeamSettings,
+				entities.StaleActionFlag, entities.StaleActionReassign)
+		}
+		if team.RequireLeadApproval && team.LeadUserID == nil {
+			return fmt.Errorf("%w: require_lead_approval needs lead_user_id", entities.ErrInvalidTeamSettings)
+		}
+		if team.LeadUserID != nil {
+			var lead entities.TeamMembership
+			result := tx.Where("user_id = ? AND team_name = ? AND role <> ?",
+				*team.LeadUserID, team.TeamName, entities.MembershipRoleObserver).First(&lead)
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: lead %q is not a member of the team", entities.ErrInvalidTeamSettings, *team.LeadUserID)
+			} else if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		err := tx.Model(&entities.Team{}).
+			Where("team_name = ?", team.TeamName).
+			Updates(map[string]interface{}{
+				"reviewer_strategy": team.ReviewerStrategy,
+				"min_reviewers":     team.MinReviewers,
+				"max_reviewers":     team.MaxReviewers,
+				"max_open_reviews":  team.MaxOpenReviews,
+
+				"min_approvals":              team.MinApprovals,
+				"block_on_changes_requested": team.BlockOnChangesRequested,
+				"require_lead_approval":      team.RequireLeadApproval,
+				"lead_user_id":               team.LeadUserID,
+
+				"review_sla_hours": team.ReviewSLAHours,
+				"stale_action":     team.StaleAction,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		if request.LeadUserID != nil {
+			if err := syncLeadRole(tx, team.TeamName, team.LeadUserID); err != nil {
+				return err
+			}
+		}
+
+		if request.FallbackTeams != nil {
+			return ts.replaceFallbackTeams(tx, team.TeamName, *request.FallbackTeams)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.GetSettings(team.TeamName)
+}
+
+func (ts *TeamService) replaceFallbackTeams(tx *gorm.DB, teamName string, fallbacks []string) error {
+	seen := make(map[string]bool, len(fallbacks))
+	for _, fallback := range fallbacks {
+		if fallback == teamName {
+			return fmt.Errorf("%w: team cannot be its own fallback", entities.ErrInvalidTeamSettings)
+		}
+		if seen[fallback] {
+			return fmt.Errorf("%w: duplicate fallback team %q", entities.ErrInvalidTeamSettings, fallback)
+		}
+		seen[fallback] = true
+	}
+
+	if len(fallbacks) > 0 {
+		var found int64
+		if err := tx.Model(&entities.Team{}).Where("team_name IN ?", fallbacks).Count(&found).Error; err != nil {
+			return err
+		}
+		if found != int64(len(fallbacks)) {
+			return fmt.Errorf("%w: unknown fallback team", entities.ErrInvalidTeamSettings)
+		}
+	}
+
+	if err := tx.Where("team_name = ?", teamName).Delete(&entities.TeamFallback{}).Error; err != nil {
+		return err
+	}
+
+	for i, fallback := range fallbacks {
+		if err := tx.Create(&entities.TeamFallback{
+			TeamName:         teamName,
+			FallbackTeamName: fallback,
+			Priority:         i,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MassDeactivateTeamUsers deactivates every active member or lead of the team,
+// whatever their home team is, and replaces them on the OPEN pull requests they
+// review. Observers are left alone. Replacements come from the team itself or,
+// since nobody there is active any more, its fallback teams.
+// With dryRun set nothing is saved and the report shows what would change.
+func (ts *TeamService) MassDeactivateTeamUsers(teamName string, dryRun bool) (*entities.ReassignmentReport, error) {
 	startTime := time.Now()
+	report := newReassignmentReport()
 
-	return ts.db.Transaction(func(tx *gorm.DB) error {
+	err := runTransaction(ts.db, dryRun, func(tx *gorm.DB) error {
 		var team entities.Team
 		if err := tx.Where("team_name = ?", teamName).First(&team).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -112,8 +843,11 @@ func (ts *TeamService) MassDeactivateTeamUsers(teamName string) error {
 			return err
 		}
 
-		result := tx.Model(&entities.User{}).
-			Where("team_name = ? AND is_active = ?", teamName, true).
+		var deactivated []entities.User
+		result := tx.Model(&deactivated).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "user_id"}}}).
+			Where("is_active = ?", true).
+			Where(reviewsForTeam, teamName, entities.MembershipRoleObserver).
 			Update("is_active", false)
 
 		if result.Error != nil {
@@ -124,7 +858,9 @@ func (ts *TeamService) MassDeactivateTeamUsers(teamName string) error {
 			return nil
 		}
 
-		if err := ts.removeReviewersFromTeamOpenPRs(tx, teamName); err != nil {
+		report.DeactivatedUsers = appendUserIDs(report.DeactivatedUsers, deactivated)
+
+		if err := reassignOpenReviews(tx, ts.assigner, report.DeactivatedUsers, reasonTeamDeactivated, report); err != nil {
 			return err
 		}
 
@@ -134,29 +870,9 @@ func (ts *TeamService) MassDeactivateTeamUsers(teamName string) error {
 
 		return nil
 	})
-}
-
-func (ts *TeamService) removeReviewersFromTeamOpenPRs(tx *gorm.DB, teamName string) error {
-	var openPRs []entities.PullRequest
-	if err := tx.Joins("JOIN users ON pull_requests.author_id = users.user_id").
-		Where("users.team_name = ? AND pull_requests.status = ?", teamName, "OPEN").
-		Find(&openPRs).Error; err != nil {
-		return err
-	}
-
-	if len(openPRs) == 0 {
-		return nil
-	}
-
-	prIDs := make([]string, len(openPRs))
-	for i, pr := range openPRs {
-		prIDs[i] = pr.PullRequestID
-	}
-
-	if err := tx.Where("pull_request_id IN (?)", prIDs).
-		Delete(&entities.PullRequestReviewer{}).Error; err != nil {
-		return err
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return report, nil
 }
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"CodeRewievService/internal/database"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens the PostgreSQL database at TEST_DATABASE_URL in a schema of
+// its own, created from db/init.sql and migrated the same way the service does
+// it. The schema is dropped when the test ends. Without TEST_DATABASE_URL the
+// test is skipped.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// Unknown URL parameters become connection settings, so every pooled
+	// connection works in the test schema.
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	db, err := gorm.Open(postgres.Open(u.String()), config)
+	if err != nil {
+		t.Fatalf("connect to test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	initSQL, err := os.ReadFile("../../db/init.sql")
+	if err != nil {
+		t.Fatalf("read init.sql: %v", err)
+	}
+	for _, statement := range strings.Split(string(initSQL), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("init.sql: %v", err)
+		}
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return db
+}
+
+// mustCreate inserts each value into db and fails the test on the first error.
+func mustCreate(t *testing.T, db *gorm.DB, values ...any) {
+	t.Helper()
+	for _, value := range values {
+		if err := db.Create(value).Error; err != nil {
+			t.Fatalf("create %T: %v", value, err)
+		}
+	}
+}
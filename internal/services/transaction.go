@@ -0,0 +1,28 @@
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// runTransaction runs fn in a transaction. With dryRun set the transaction is
+// always rolled back, so fn can run the full logic and report what it would change.
+func runTransaction(db *gorm.DB, dryRun bool, fn func(tx *gorm.DB) error) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	return err
+}
@@ -2,44 +2,106 @@ package services
 
 import (
 	"CodeRewievService/internal/entities"
+	"CodeRewievService/internal/interfaces"
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
 )
 
 type UserService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	assigner *reviewerAssigner
 }
 
-func NewUserService(db *gorm.DB) *UserService {
+func NewUserService(db *gorm.DB, selectors map[string]interfaces.ReviewerSelector) *UserService {
 	return &UserService{
-		db: db,
+		db:       db,
+		assigner: newReviewerAssigner(selectors),
 	}
 }
 
-func (us *UserService) SetIsActive(user *entities.User) (*entities.User, error) {
+// SetIsActive updates the flag of a user. Deactivating a user also replaces
+// them on every OPEN pull request they review, in the same transaction.
+func (us *UserService) SetIsActive(user *entities.User) (*entities.User, *entities.ReassignmentReport, error) {
 	if user == nil {
-		return nil, errors.New("user cannot be nil")
+		return nil, nil, errors.New("user cannot be nil")
 	}
 
 	if user.UserID == "" {
-		return nil, errors.New("user_id cannot be empty")
+		return nil, nil, errors.New("user_id cannot be empty")
 	}
 
 	var existingUser entities.User
-	result := us.db.Where("user_id = ?", user.UserID).First(&existingUser)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, errors.New("user not found")
-	} else if result.Error != nil {
-		return nil, result.Error
+	report := newReassignmentReport()
+
+	err := us.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", user.UserID).
+			First(&existingUser)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s", entities.ErrUserNotFound, user.UserID)
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		existingUser.IsActive = user.IsActive
+
+		if err := tx.Model(&existingUser).Update("is_active", existingUser.IsActive).Error; err != nil {
+			return err
+		}
+
+		if existingUser.IsActive {
+			return nil
+		}
+
+		report.DeactivatedUsers = append(report.DeactivatedUsers, existingUser.UserID)
+		return reassignOpenReviews(tx, us.assigner, report.DeactivatedUsers, reasonUserDeactivated, report)
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	existingUser.IsActive = user.IsActive
+	return &existingUser, report, nil
+}
 
-	if err := us.db.Save(&existingUser).Error; err != nil {
-		return nil, err
+func newReassignmentReport() *entities.ReassignmentReport {
+	return &entities.ReassignmentReport{
+		DeactivatedUsers: []string{},
+		ReviewedPRs:      []entities.PullRequestReassignment{},
+	}
+}
+
+// reassignOpenReviews replaces userIDs, who must already be inactive, on every
+// OPEN pull request they review and adds the outcome for each pull request to report.
+// Only those users are removed; other reviewers are kept. The affected pull
+// requests are locked up front in id order so concurrent calls cannot deadlock.
+func reassignOpenReviews(tx *gorm.DB, assigner *reviewerAssigner, userIDs []string, reason string, report *entities.ReassignmentReport) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var prs []entities.PullRequest
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("AssignedReviewers").
+		Preload("Author").
+		Where("status = ? AND pull_request_id IN (SELECT pull_request_id FROM pull_request_reviewers WHERE user_id IN ?)",
+			entities.PRStatusOpen, userIDs).
+		Order("pull_request_id").
+		Find(&prs).Error
+	if err != nil {
+		return err
+	}
+
+	reviewed, err := replaceReviewers(tx, assigner, prs, userIDs, entities.AssignmentEventUnassignedDeactivation, reason)
+	if err != nil {
+		return err
 	}
+	report.ReviewedPRs = append(report.ReviewedPRs, reviewed...)
 
-	return &existingUser, nil
+	return nil
 }
 
 func (us *UserService) GetReview(userID string) (*entities.UserReview, error) {
@@ -56,7 +118,7 @@ func (us *UserService) GetReview(userID string) (*entities.UserReview, error) {
 	}
 
 	var pullRequests []entities.PullRequest
-	err := us.db.
+	err := us.db.Preload("AssignedReviewers").
 		Joins("JOIN pull_request_reviewers ON pull_requests.pull_request_id = pull_request_reviewers.pull_request_id").
 		Where("pull_request_reviewers.user_id = ?", userID).
 		Find(&pullRequests).Error
@@ -76,3 +138,130 @@ func (us *UserService) GetReview(userID string) (*entities.UserReview, error) {
 
 	return userReview, nil
 }
+
+func (us *UserService) SetCapacity(userID string, maxOpenReviews *int) (*entities.User, error) {
+	if userID == "" {
+		return nil, errors.New("user_id cannot be empty")
+	}
+
+	if maxOpenReviews != nil && *maxOpenReviews < 0 {
+		return nil, entities.ErrInvalidCapacity
+	}
+
+	var user entities.User
+	result := us.db.Where("user_id = ?", userID).First(&user)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrUserNotFound
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if err := us.db.Model(&user).Update("max_open_reviews", maxOpenReviews).Error; err != nil {
+		return nil, err
+	}
+	user.MaxOpenReviews = maxOpenReviews
+
+	return &user, nil
+}
+
+func (us *UserService) SetSkills(userID string, skills []string) ([]string, error) {
+	if userID == "" {
+		return nil, errors.New("user_id cannot be empty")
+	}
+
+	skills = normalizeSkills(skills)
+
+	err := us.db.Transaction(func(tx *gorm.DB) error {
+		var user entities.User
+		result := tx.Where("user_id = ?", userID).First(&user)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return entities.ErrUserNotFound
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.UserSkill{}).Error; err != nil {
+			return err
+		}
+
+		for _, skill := range skills {
+			if err := tx.Create(&entities.UserSkill{UserID: userID, Skill: skill}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return skills, nil
+}
+
+func (us *UserService) GetSkills(userID string) ([]string, error) {
+	if userID == "" {
+		return nil, errors.New("user_id cannot be empty")
+	}
+
+	var user entities.User
+	result := us.db.Where("user_id = ?", userID).First(&user)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, entities.ErrUserNotFound
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+
+	skills := []string{}
+	err := us.db.Model(&entities.UserSkill{}).
+		Where("user_id = ?", userID).
+		Order("skill").
+		Pluck("skill", &skills).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return skills, nil
+}
+
+func attachSkills(db *gorm.DB, users []entities.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	var userSkills []entities.UserSkill
+	err := db.Where("user_id IN ?", appendUserIDs(nil, users)).
+		Order("skill").
+		Find(&userSkills).Error
+	if err != nil {
+		return err
+	}
+
+	skills := make(map[string][]string, len(users))
+	for _, userSkill := range userSkills {
+		skills[userSkill.UserID] = append(skills[userSkill.UserID], userSkill.Skill)
+	}
+
+	for i := range users {
+		users[i].Skills = skills[users[i].UserID]
+	}
+
+	return nil
+}
+
+// normalizeSkills lowercases and trims skill tags, dropping empty and duplicate ones.
+func normalizeSkills(skills []string) []string {
+	seen := make(map[string]bool, len(skills))
+	normalized := []string{}
+	for _, skill := range skills {
+		skill = strings.ToLower(strings.TrimSpace(skill))
+		if skill == "" || seen[skill] {
+			continue
+		}
+		seen[skill] = true
+		normalized = append(normalized, skill)
+	}
+
+	sort.Strings(normalized)
+	return normalized
+}
@@ -16,6 +16,8 @@ type LoadTestResult struct {
 	AvgResponseTime    time.Duration
 	MaxResponseTime    time.Duration
 	ErrorRate          float64
+
+	MaxDeactivateTime time.Duration
 }
 
 func runLoadTest() *LoadTestResult {
@@ -32,6 +34,7 @@ func runLoadTest() *LoadTestResult {
 		failed          int64
 		totalDuration   int64
 		maxResponseTime int64
+		maxDeactivate   int64
 		wg              sync.WaitGroup
 	)
 
@@ -53,18 +56,34 @@ func runLoadTest() *LoadTestResult {
 			start := time.Now()
 
 			var url string
-			switch reqNum % 3 {
+			method := http.MethodGet
+			switch reqNum % 4 {
 			case 0:
 				url = fmt.Sprintf("%s/team/get?team_name=backend", baseURL)
 			case 1:
 				url = fmt.Sprintf("%s/users/getReview?user_id=user_backend_1", baseURL)
 			case 2:
 				url = fmt.Sprintf("%s/team/get?team_name=frontend", baseURL)
+			case 3:
+				// A dry run goes through the full reassignment and rolls it back.
+				url = fmt.Sprintf("%s/team/deactivate?team_name=backend&dry_run=true", baseURL)
+				method = http.MethodPost
 			}
 
-			resp, err := http.Get(url)
+			request, err := http.NewRequest(method, url, nil)
+			if err != nil {
+				atomic.AddInt64(&failed, 1)
+				return
+			}
+			resp, err := http.DefaultClient.Do(request)
 			duration := time.Since(start).Nanoseconds()
 
+			if method == http.MethodPost {
+				if currentMax := atomic.LoadInt64(&maxDeactivate); duration > currentMax {
+					atomic.CompareAndSwapInt64(&maxDeactivate, currentMax, duration)
+				}
+			}
+
 			atomic.AddInt64(&totalRequests, 1)
 			atomic.AddInt64(&totalDuration, duration)
 
@@ -99,6 +118,7 @@ func runLoadTest() *LoadTestResult {
 		result.AvgResponseTime = time.Duration(totalDuration / totalRequests)
 		result.MaxResponseTime = time.Duration(maxResponseTime)
 		result.ErrorRate = float64(failed) / float64(totalRequests)
+		result.MaxDeactivateTime = time.Duration(maxDeactivate)
 	}
 
 	return result
@@ -116,6 +136,7 @@ func main() {
 	fmt.Printf("Average Response Time: %v\n", result.AvgResponseTime)
 	fmt.Printf("Max Response Time: %v\n", result.MaxResponseTime)
 	fmt.Printf("Error Rate: %.4f%%\n", result.ErrorRate*100)
+	fmt.Printf("Max Team Deactivation Time: %v\n", result.MaxDeactivateTime)
 
 	if result.AvgResponseTime < 300*time.Millisecond {
 		fmt.Printf("SLI Response Time: PASSED (< 300ms)\n")
@@ -123,6 +144,12 @@ func main() {
 		fmt.Printf("SLI Response Time: FAILED\n")
 	}
 
+	if result.MaxDeactivateTime < 100*time.Millisecond {
+		fmt.Printf("SLI Team Deactivation Time: PASSED (< 100ms)\n")
+	} else {
+		fmt.Printf("SLI Team Deactivation Time: FAILED\n")
+	}
+
 	if result.ErrorRate < 0.001 {
 		fmt.Printf("SLI Success Rate: PASSED (> 99.9%%)\n")
 	} else {